@@ -1,20 +1,26 @@
 package main
 
-import "github.com/Danis0n/goreg/internal/goreg"
+import (
+	"log"
+
+	"github.com/Danis0n/goreg/internal/goreg"
+)
 
 func setupServer() {
 
 	cfg, err := goreg.NewGoregServerConfig(8079)
 	if err != nil {
-		// do smth
+		log.Fatal(err)
 	}
 
 	server, err := goreg.NewGoregServer(cfg)
 	if err != nil {
-		// do smth
+		log.Fatal(err)
 	}
 
-	server.Start()
+	if err := server.Start(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func setupClient() {
@@ -26,12 +32,12 @@ func setupClient() {
 		90,
 	)
 	if err != nil {
-		// do smth
+		log.Fatal(err)
 	}
 
 	client, err := goreg.NewGoregClient(cfg)
 	if err != nil {
-		// do smth
+		log.Fatal(err)
 	}
 
 	client.Start()
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func main() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	os.Exit(run(os.Args[1:], signals, os.Stderr))
+}
+
+// run starts the registry and drains it on the first value from signals. It
+// returns the exit code: 2 for usage errors, 1 for config and serve errors.
+func run(args []string, signals <-chan os.Signal, stderr io.Writer) int {
+	fs := flag.NewFlagSet("goreg-server", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "YAML or JSON config file (env GOREG_CONFIG)")
+	port := fs.Int("port", 0, "listen port, overrides the config file and GOREG_PORT")
+	logLevel := fs.String("log-level", "info", "log level: debug, info, warn or error")
+	drainTimeout := fs.Duration("drain-timeout", 15*time.Second, "how long to wait for in-flight requests on shutdown")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	logger, err := newLogger(*logLevel, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "goreg-server: "+err.Error())
+		return 2
+	}
+	defer logger.Sync()
+
+	if *configPath == "" {
+		*configPath = os.Getenv("GOREG_CONFIG")
+	}
+
+	cfg, err := goreg.LoadServerConfig(*configPath)
+	if err != nil {
+		logger.Error("goreg-server: " + err.Error())
+		return 1
+	}
+
+	if *port != 0 {
+		cfg.Port = *port
+	}
+
+	srv, err := goreg.NewGoregServer(cfg, server.WithLogger(logger))
+	if err != nil {
+		logger.Error("goreg-server: " + err.Error())
+		return 1
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			logger.Error("goreg-server: " + err.Error())
+			return 1
+		}
+		return 0
+	case sig := <-signals:
+		logger.Info("goreg-server: " + sig.String() + " received, draining for up to " + drainTimeout.String())
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("goreg-server: shutdown: " + err.Error())
+		return 1
+	}
+
+	if err := <-errCh; err != nil && !errors.Is(err, context.Canceled) {
+		logger.Error("goreg-server: " + err.Error())
+		return 1
+	}
+
+	logger.Info("goreg-server: stopped")
+	return 0
+}
+
+func newLogger(level string, w io.Writer) (*zap.Logger, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return zap.New(zapcore.NewCore(encoder, zapcore.AddSync(w), lvl), zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// syncBuffer is written by the server goroutine and read by the test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestRun_Errors(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(invalid, []byte("prot: 9000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{"Unknown flag", []string{"-frobnicate"}, 2, "flag provided but not defined: -frobnicate"},
+		{"Invalid log level", []string{"-log-level", "loud"}, 2, "goreg-server: unrecognized level"},
+		{"Missing config file", []string{"-config", invalid + ".missing"}, 1, "goreg-server: goreg: read config"},
+		{"Invalid config file", []string{"-config", invalid}, 1, "field prot not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOREG_CONFIG", "")
+
+			var stderr bytes.Buffer
+			if code := run(tt.args, nil, &stderr); code != tt.wantCode {
+				t.Fatalf("run() = %d, want %d (stderr %q)", code, tt.wantCode, stderr.String())
+			}
+
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("expected stderr to contain %q, got %q", tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRun_SignalDrain(t *testing.T) {
+	t.Setenv("GOREG_CONFIG", "")
+	port := strconv.Itoa(freePort(t))
+
+	signals := make(chan os.Signal, 1)
+	stderr := &syncBuffer{}
+	codeCh := make(chan int, 1)
+	go func() {
+		codeCh <- run([]string{"-port", port, "-drain-timeout", "5s"}, signals, stderr)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get("http://127.0.0.1:" + port + "/health")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not come up: %v (stderr %q)", err, stderr.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	signals <- syscall.SIGTERM
+
+	select {
+	case code := <-codeCh:
+		if code != 0 {
+			t.Fatalf("run() = %d, want 0 (stderr %q)", code, stderr.String())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("run did not return after SIGTERM")
+	}
+
+	for _, want := range []string{"terminated received, draining for up to 5s", "goreg-server: stopped"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("expected stderr to contain %q, got %q", want, stderr.String())
+		}
+	}
+
+	if _, err := http.Get("http://127.0.0.1:" + port + "/health"); err == nil {
+		t.Error("expected the listener to be closed after the drain")
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/client"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"gopkg.in/yaml.v3"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, app *app, args []string) error
+}
+
+type app struct {
+	registry *client.Registry
+	output   string
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+var commands = []command{
+	{"list", "list registered services", runList},
+	{"get", "get <name>: show one service", runGet},
+	{"register", "register -name <name> -callback <url> [-port <port>]: register a service", runRegister},
+	{"deregister", "deregister <name>: remove a service", runDeregister},
+	{"watch", "watch [-interval 5s]: print changes of the service list", runWatch},
+	{"health", "check that the registry is reachable", runHealth},
+	{"export", "export [-file <path>]: dump every service as json or yaml", runExport},
+	{"import", "import <file>: register every service from an export file", runImport},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	services, err := a.registry.List(ctx)
+	if err != nil {
+		return err
+	}
+	return printServices(a.stdout, a.output, services)
+}
+
+func runGet(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: get <name>")
+	}
+
+	service, err := a.registry.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return printServices(a.stdout, a.output, []*server.Service{service})
+}
+
+func runRegister(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("register", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	name := fs.String("name", "", "service name")
+	callback := fs.String("callback", "", "callback url probed by the registry")
+	port := fs.Int("port", 0, "service port")
+	address := fs.String("address", "", "address to scrape, defaults to the callback host")
+	tags := fs.String("tags", "", "comma separated service tags")
+	metadata := metadataFlag{}
+	fs.Var(metadata, "meta", "service metadata as key=value, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *name == "" || *callback == "" {
+		return errors.New("usage: register -name <name> -callback <url> [-port <port>] [-address <host>] [-tags <a,b>] [-meta <k=v>]")
+	}
+
+	var tagList []string
+	if *tags != "" {
+		tagList = strings.Split(*tags, ",")
+	}
+
+	response, err := a.registry.Register(ctx, client.RegisterRequest{
+		Name:     *name,
+		Callback: *callback,
+		Port:     *port,
+		Address:  *address,
+		Tags:     tagList,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.output == outputTable {
+		fmt.Fprintln(a.stdout, "registered "+*name+" (hash "+response.Hash+")")
+		return nil
+	}
+	return printValue(a.stdout, a.output, response)
+}
+
+func runDeregister(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: deregister <name>")
+	}
+
+	if err := a.registry.Deregister(ctx, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stdout, "deregistered "+args[0])
+	return nil
+}
+
+func runWatch(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	interval := fs.Duration("interval", 5*time.Second, "longest wait of each blocking query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	onError := func(err error) {
+		fmt.Fprintln(a.stderr, "watch: "+err.Error())
+	}
+
+	var previous map[string]*server.Service
+	for services := range a.registry.Watch(ctx, *interval, onError) {
+		if previous == nil || a.output != outputTable {
+			if err := printServices(a.stdout, a.output, services); err != nil {
+				return err
+			}
+		} else {
+			printDiff(a.stdout, previous, services)
+		}
+
+		previous = make(map[string]*server.Service, len(services))
+		for _, service := range services {
+			previous[service.Name] = service
+		}
+	}
+
+	return nil
+}
+
+// metadataFlag collects repeated -meta key=value flags.
+type metadataFlag map[string]string
+
+func (m metadataFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m metadataFlag) Set(raw string) error {
+	key, value, ok := strings.Cut(raw, "=")
+	if !ok || key == "" {
+		return errors.New("metadata must be key=value")
+	}
+	m[key] = value
+	return nil
+}
+
+func printDiff(w io.Writer, previous map[string]*server.Service, services []*server.Service) {
+	now := time.Now().Format(time.RFC3339)
+	seen := make(map[string]bool, len(services))
+
+	for _, service := range services {
+		seen[service.Name] = true
+
+		old, ok := previous[service.Name]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "%s\tADDED\t%s\t%s\n", now, service.Name, service.Callback)
+		case !reflect.DeepEqual(old, service):
+			fmt.Fprintf(w, "%s\tCHANGED\t%s\t%s\n", now, service.Name, service.Callback)
+		}
+	}
+
+	for name, service := range previous {
+		if !seen[name] {
+			fmt.Fprintf(w, "%s\tREMOVED\t%s\t%s\n", now, name, service.Callback)
+		}
+	}
+}
+
+func runHealth(ctx context.Context, a *app, args []string) error {
+	health, err := a.registry.Health(ctx)
+	if err != nil {
+		return err
+	}
+
+	if a.output == outputTable {
+		fmt.Fprintf(a.stdout, "registry is %s, %d services registered\n", health.Status, health.Services)
+		return nil
+	}
+	return printValue(a.stdout, a.output, health)
+}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	file := fs.String("file", "", "write to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	services, err := a.registry.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	format := a.output
+	if format == outputTable {
+		format = outputJSON
+	}
+
+	w := a.stdout
+	if *file != "" {
+		if strings.EqualFold(filepath.Ext(*file), ".yaml") || strings.EqualFold(filepath.Ext(*file), ".yml") {
+			format = outputYAML
+		}
+
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return printValue(w, format, services)
+}
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: import <file>")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	var services []*server.Service
+	if strings.EqualFold(filepath.Ext(args[0]), ".json") {
+		err = json.Unmarshal(data, &services)
+	} else {
+		err = yaml.Unmarshal(data, &services)
+	}
+	if err != nil {
+		return errors.New("parse " + args[0] + ": " + err.Error())
+	}
+
+	var errs []error
+	for _, service := range services {
+		_, err := a.registry.Register(ctx, client.RegisterRequest{
+			Name:     service.Name,
+			Callback: service.Callback,
+			Port:     service.Port,
+			Address:  service.Address,
+			Tags:     service.Tags,
+			Metadata: service.Metadata,
+		})
+		if err != nil {
+			errs = append(errs, errors.New(service.Name+": "+err.Error()))
+			continue
+		}
+		fmt.Fprintln(a.stdout, "imported "+service.Name)
+	}
+
+	return errors.Join(errs...)
+}
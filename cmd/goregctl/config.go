@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/Danis0n/goreg/internal/goreg/tlsprovider"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultAddress = "http://localhost:8079"
+
+type config struct {
+	Address string             `yaml:"address"`
+	Token   string             `yaml:"token"`
+	TLS     tlsprovider.Config `yaml:"tls"`
+}
+
+// loadConfig resolves the registry address and token. Flags win over GOREG_*
+// environment variables, which win over the config file.
+func loadConfig(fs *flag.FlagSet, path, address, token string) (config, error) {
+	var cfg config
+
+	if path == "" {
+		path = os.Getenv("GOREG_CONFIG")
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config{}, errors.New("read config: " + err.Error())
+		}
+
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return config{}, errors.New("parse config " + path + ": " + err.Error())
+		}
+	}
+
+	if v, ok := os.LookupEnv("GOREG_ADDRESS"); ok {
+		cfg.Address = v
+	}
+	if v, ok := os.LookupEnv("GOREG_TOKEN"); ok {
+		cfg.Token = v
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Address = address
+		case "token":
+			cfg.Token = token
+		}
+	})
+
+	if cfg.Address == "" {
+		cfg.Address = defaultAddress
+	}
+
+	return cfg, nil
+}
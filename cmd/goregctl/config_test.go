@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goregctl.yaml")
+	if err := os.WriteFile(path, []byte("address: http://file:8079\ntoken: file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantAddress string
+		wantToken   string
+	}{
+		{"Defaults", nil, nil, defaultAddress, ""},
+		{"Config file", []string{"-config", path}, nil, "http://file:8079", "file-token"},
+		{"Config from env", nil, map[string]string{"GOREG_CONFIG": path}, "http://file:8079", "file-token"},
+		{"Env wins over file", []string{"-config", path}, map[string]string{"GOREG_ADDRESS": "http://env:8079"}, "http://env:8079", "file-token"},
+		{"Flags win over env", []string{"-config", path, "-addr", "http://flag:8079", "-token", "flag-token"}, map[string]string{"GOREG_ADDRESS": "http://env:8079", "GOREG_TOKEN": "env-token"}, "http://flag:8079", "flag-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"GOREG_CONFIG", "GOREG_ADDRESS", "GOREG_TOKEN"} {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			fs := flag.NewFlagSet("goregctl", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			address := fs.String("addr", "", "")
+			token := fs.String("token", "", "")
+			configPath := fs.String("config", "", "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := loadConfig(fs, *configPath, *address, *token)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if cfg.Address != tt.wantAddress || cfg.Token != tt.wantToken {
+				t.Errorf("loadConfig() = %s %s, want %s %s", cfg.Address, cfg.Token, tt.wantAddress, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_MissingFile(t *testing.T) {
+	fs := flag.NewFlagSet("goregctl", flag.ContinueOnError)
+	if _, err := loadConfig(fs, filepath.Join(t.TempDir(), "missing.yaml"), "", ""); err == nil {
+		t.Fatal("expected error for missing config file, got nil")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Danis0n/goreg/internal/goreg/client"
+	"github.com/Danis0n/goreg/internal/goreg/tlsprovider"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("goregctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	address := fs.String("addr", "", "registry address (env GOREG_ADDRESS, default "+defaultAddress+")")
+	token := fs.String("token", "", "acl token (env GOREG_TOKEN)")
+	configPath := fs.String("config", "", "config file with address, token and tls (env GOREG_CONFIG)")
+	output := fs.String("o", outputTable, "output format: table, json or yaml")
+	fs.Usage = func() { usage(fs) }
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		usage(fs)
+		return 2
+	}
+
+	cmd, ok := findCommand(fs.Arg(0))
+	if !ok {
+		fmt.Fprintln(stderr, "goregctl: unknown command "+fs.Arg(0))
+		usage(fs)
+		return 2
+	}
+
+	if err := validateOutput(*output); err != nil {
+		fmt.Fprintln(stderr, "goregctl: "+err.Error())
+		return 2
+	}
+
+	cfg, err := loadConfig(fs, *configPath, *address, *token)
+	if err != nil {
+		fmt.Fprintln(stderr, "goregctl: "+err.Error())
+		return 1
+	}
+
+	var opts []client.Option
+	if cfg.TLS.Enabled() {
+		reloader, err := tlsprovider.NewReloader(cfg.TLS)
+		if err != nil {
+			fmt.Fprintln(stderr, "goregctl: "+err.Error())
+			return 1
+		}
+		opts = append(opts, client.WithHTTPClient(reloader.HTTPClient()))
+	}
+
+	registry, err := client.NewRegistry(cfg.Address, cfg.Token, opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, "goregctl: "+err.Error())
+		return 1
+	}
+
+	a := &app{
+		registry: registry,
+		output:   *output,
+		stdout:   stdout,
+		stderr:   stderr,
+	}
+
+	if err := cmd.run(ctx, a, fs.Args()[1:]); err != nil {
+		fmt.Fprintln(stderr, "goregctl: "+cmd.name+": "+err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintln(out, "usage: goregctl [flags] <command> [args]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-12s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "flags:")
+	fs.PrintDefaults()
+}
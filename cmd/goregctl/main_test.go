@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func setupTestRegistry(t *testing.T) string {
+	t.Helper()
+
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079}, server.WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+
+	t.Setenv("GOREG_CONFIG", "")
+	t.Setenv("GOREG_TOKEN", "")
+	return ts.URL
+}
+
+func runCtl(t *testing.T, address string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+
+	var out, errOut bytes.Buffer
+	code = run(context.Background(), append([]string{"-addr", address}, args...), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun_Commands(t *testing.T) {
+	address := setupTestRegistry(t)
+	exportFile := filepath.Join(t.TempDir(), "services.json")
+
+	// Steps share one registry and run in order.
+	steps := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout []string
+		wantStderr string
+	}{
+		{"Empty list", []string{"list"}, 0, []string{"NAME", "CALLBACK", "HASH"}, ""},
+		{"Register", []string{"register", "-name", "orders", "-callback", "http://orders:8080/callback", "-port", "8080", "-tags", "api,v1"}, 0, []string{"registered orders (hash "}, ""},
+		{"Register without callback", []string{"register", "-name", "billing"}, 1, nil, "goregctl: register: usage: register"},
+		{"Register invalid name", []string{"register", "-name", "Bad Name", "-callback", "http://orders:8080/callback"}, 1, nil, "goregctl: register: "},
+		{"Get", []string{"get", "orders"}, 0, []string{"orders", "http://orders:8080/callback"}, ""},
+		{"Get json", []string{"-o", "json", "get", "orders"}, 0, []string{`"name": "orders"`, `"port": 8080`}, ""},
+		{"Get without name", []string{"get"}, 1, nil, "goregctl: get: usage: get <name>"},
+		{"List", []string{"list"}, 0, []string{"orders", "http://orders:8080/callback"}, ""},
+		{"List yaml", []string{"-o", "yaml", "list"}, 0, []string{"- name: orders"}, ""},
+		{"Export", []string{"export", "-file", exportFile}, 0, nil, ""},
+		{"Deregister", []string{"deregister", "orders"}, 0, []string{"deregistered orders"}, ""},
+		{"Get deregistered", []string{"get", "orders"}, 1, nil, "goregctl: get: "},
+		{"Deregister again", []string{"deregister", "orders"}, 1, nil, "goregctl: deregister: "},
+		{"Import", []string{"import", exportFile}, 0, []string{"imported orders"}, ""},
+		{"Import missing file", []string{"import", exportFile + ".missing"}, 1, nil, "goregctl: import: "},
+		{"List imported", []string{"list"}, 0, []string{"orders", "http://orders:8080/callback"}, ""},
+		{"Unknown command", []string{"frobnicate"}, 2, nil, "goregctl: unknown command frobnicate"},
+		{"Unknown output", []string{"-o", "xml", "list"}, 2, nil, "goregctl: unknown output format: xml"},
+		{"No command", nil, 2, nil, "usage: goregctl"},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			code, stdout, stderr := runCtl(t, address, step.args...)
+
+			if code != step.wantCode {
+				t.Fatalf("run() = %d, want %d (stderr %q)", code, step.wantCode, stderr)
+			}
+
+			for _, want := range step.wantStdout {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("expected stdout to contain %q, got %q", want, stdout)
+				}
+			}
+
+			if !strings.Contains(stderr, step.wantStderr) {
+				t.Errorf("expected stderr to contain %q, got %q", step.wantStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestRun_Export(t *testing.T) {
+	address := setupTestRegistry(t)
+
+	if code, _, stderr := runCtl(t, address, "register", "-name", "orders", "-callback", "http://orders:8080/callback", "-meta", "team=core"); code != 0 {
+		t.Fatalf("register failed: %s", stderr)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		decode func([]byte, any) error
+	}{
+		{"Stdout defaults to json", []string{"export"}, json.Unmarshal},
+		{"Json file", []string{"export", "-file", filepath.Join(t.TempDir(), "services.json")}, json.Unmarshal},
+		{"Yaml file", []string{"export", "-file", filepath.Join(t.TempDir(), "services.yaml")}, yaml.Unmarshal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCtl(t, address, tt.args...)
+			if code != 0 {
+				t.Fatalf("run() = %d, want 0 (stderr %q)", code, stderr)
+			}
+
+			data := []byte(stdout)
+			if len(tt.args) > 1 {
+				var err error
+				if data, err = os.ReadFile(tt.args[2]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var services []*server.Service
+			if err := tt.decode(data, &services); err != nil {
+				t.Fatalf("expected exported services, got %v: %s", err, data)
+			}
+
+			if len(services) != 1 || services[0].Name != "orders" || services[0].Metadata["team"] != "core" {
+				t.Errorf("unexpected export: %+v", services)
+			}
+		})
+	}
+}
+
+func TestRun_ImportErrors(t *testing.T) {
+	address := setupTestRegistry(t)
+
+	file := filepath.Join(t.TempDir(), "services.yaml")
+	content := "- name: orders\n  callback: http://orders:8080/callback\n- name: Bad Name\n  callback: http://bad:8080/callback\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCtl(t, address, "import", file)
+	if code != 1 {
+		t.Fatalf("run() = %d, want 1", code)
+	}
+
+	if !strings.Contains(stdout, "imported orders") {
+		t.Errorf("expected valid service to be imported, got %q", stdout)
+	}
+
+	if !strings.Contains(stderr, "Bad Name: ") {
+		t.Errorf("expected invalid service to be reported, got %q", stderr)
+	}
+}
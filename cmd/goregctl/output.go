@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func validateOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return errors.New("unknown output format: " + format)
+	}
+}
+
+func printServices(w io.Writer, format string, services []*server.Service) error {
+	if format == outputTable {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tCALLBACK\tHASH")
+		for _, service := range services {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", service.Name, service.Callback, service.Hash)
+		}
+		return tw.Flush()
+	}
+
+	return printValue(w, format, services)
+}
+
+func printValue(w io.Writer, format string, v any) error {
+	switch format {
+	case outputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case outputTable:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/Danis0n/goreg/internal/goreg/validation"
+)
+
+var ErrNoAdvertiseAddress = errors.New("goreg->[client]: no routable address to advertise")
+
+// AdvertiseConfig selects the address advertised to the registry when
+// ClientConfig.Callback is empty.
+type AdvertiseConfig struct {
+	// Address skips detection, e.g. from GOREG_ADVERTISE_ADDRESS with
+	// LoadClientConfig.
+	Address string `yaml:"address" json:"address"`
+	// Include restricts the candidates to these CIDRs, e.g. 10.0.0.0/8.
+	Include []string `yaml:"include" json:"include"`
+	// Exclude drops the candidates in these CIDRs, e.g. container bridges.
+	Exclude []string `yaml:"exclude" json:"exclude"`
+	// IPv6 prefers IPv6 addresses. Either family falls back to the other.
+	IPv6 bool `yaml:"ipv6" json:"ipv6"`
+}
+
+// AdvertiseAddress returns the host the registry should reach this process
+// at: cfg.Address when set, otherwise a routable address of an up,
+// non-loopback interface allowed by cfg.
+func AdvertiseAddress(cfg AdvertiseConfig) (string, error) {
+	if cfg.Address != "" {
+		return cfg.Address, nil
+	}
+
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+
+	ip, err := pickAddress(addrs, cfg)
+	if err != nil {
+		return "", err
+	}
+	return ip.String(), nil
+}
+
+func interfaceAddrs() ([]net.Addr, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []net.Addr
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, ifaceAddrs...)
+	}
+	return addrs, nil
+}
+
+// pickAddress returns the first global unicast address of addrs allowed by
+// cfg, preferring the family cfg asks for.
+func pickAddress(addrs []net.Addr, cfg AdvertiseConfig) (net.IP, error) {
+	include, exclude := parseCIDRs(cfg.Include), parseCIDRs(cfg.Exclude)
+
+	var v4, v6 net.IP
+	for _, addr := range addrs {
+		var ip net.IP
+		switch a := addr.(type) {
+		case *net.IPNet:
+			ip = a.IP
+		case *net.IPAddr:
+			ip = a.IP
+		}
+
+		if ip == nil || !ip.IsGlobalUnicast() {
+			continue
+		}
+		if len(include) > 0 && !containsIP(include, ip) {
+			continue
+		}
+		if containsIP(exclude, ip) {
+			continue
+		}
+
+		if ip.To4() != nil {
+			if v4 == nil {
+				v4 = ip
+			}
+		} else if v6 == nil {
+			v6 = ip
+		}
+	}
+
+	preferred, fallback := v4, v6
+	if cfg.IPv6 {
+		preferred, fallback = v6, v4
+	}
+
+	switch {
+	case preferred != nil:
+		return preferred, nil
+	case fallback != nil:
+		return fallback, nil
+	}
+	return nil, ErrNoAdvertiseAddress
+}
+
+// parseCIDRs skips invalid prefixes, which ValidateClientConfig reports.
+func parseCIDRs(raw []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(raw))
+	for _, cidr := range raw {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	return nets
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe renders the address selection of cfg for logs.
+func (cfg AdvertiseConfig) describe() string {
+	if cfg.Address != "" {
+		return "address " + cfg.Address
+	}
+	return "include [" + strings.Join(cfg.Include, ", ") + "] exclude [" + strings.Join(cfg.Exclude, ", ") +
+		"] ipv6 " + strconv.FormatBool(cfg.IPv6)
+}
+
+// advertiseCallback is the callback address of a client configured without
+// one. When no address can be found it advertises the unspecified address,
+// which the registry replaces with the source address of the registration.
+func advertiseCallback(cfg ClientConfig) (string, error) {
+	scheme := "http"
+	if cfg.TLS.HasCertificate() {
+		scheme = "https"
+	}
+
+	host, err := AdvertiseAddress(cfg.Advertise)
+	if err != nil {
+		host = "0.0.0.0"
+		if cfg.Advertise.IPv6 {
+			host = "::"
+		}
+	}
+
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(cfg.Port)), err
+}
+
+func validateAdvertiseConfig(cfg AdvertiseConfig) error {
+	var errs []error
+	for i, cidr := range cfg.Include {
+		errs = append(errs, validation.CIDR("advertise.include["+strconv.Itoa(i)+"]", cidr))
+	}
+	for i, cidr := range cfg.Exclude {
+		errs = append(errs, validation.CIDR("advertise.exclude["+strconv.Itoa(i)+"]", cidr))
+	}
+	return errors.Join(errs...)
+}
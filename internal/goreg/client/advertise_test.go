@@ -0,0 +1,85 @@
+package client
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func ipNet(cidr string) net.Addr {
+	ip, ipNet, _ := net.ParseCIDR(cidr)
+	ipNet.IP = ip
+	return ipNet
+}
+
+func TestPickAddress(t *testing.T) {
+	addrs := []net.Addr{
+		ipNet("fe80::1/64"),
+		ipNet("172.17.0.1/16"),
+		ipNet("10.0.0.7/8"),
+		ipNet("fd00::7/64"),
+	}
+
+	tests := []struct {
+		name string
+		cfg  AdvertiseConfig
+		want string
+	}{
+		{"first ipv4", AdvertiseConfig{}, "172.17.0.1"},
+		{"exclude", AdvertiseConfig{Exclude: []string{"172.16.0.0/12"}}, "10.0.0.7"},
+		{"include", AdvertiseConfig{Include: []string{"10.0.0.0/8"}}, "10.0.0.7"},
+		{"prefer ipv6", AdvertiseConfig{IPv6: true}, "fd00::7"},
+		{"ipv4 fallback", AdvertiseConfig{IPv6: true, Include: []string{"10.0.0.0/8"}}, "10.0.0.7"},
+		{"ipv6 fallback", AdvertiseConfig{Include: []string{"fd00::/8"}}, "fd00::7"},
+		{"nothing left", AdvertiseConfig{Include: []string{"192.168.0.0/16"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, err := pickAddress(addrs, tt.cfg)
+			if tt.want == "" {
+				assert.ErrorIs(t, err, ErrNoAdvertiseAddress)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ip.String())
+		})
+	}
+}
+
+func TestAdvertiseCallback(t *testing.T) {
+	cfg := ClientConfig{Port: 8080, Advertise: AdvertiseConfig{Address: "orders.internal"}}
+	callback, err := advertiseCallback(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://orders.internal:8080", callback)
+
+	cfg.Advertise.Address = "fd00::7"
+	callback, err = advertiseCallback(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://[fd00::7]:8080", callback)
+
+	cfg.Registrator, cfg.Name = "http://registrator.url", "orders"
+	client, err := NewClient(cfg, WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://[fd00::7]:8080/callback", client.store.CallbackURL())
+}
+
+func TestNewClient_AdvertiseError(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := ClientConfig{Registrator: "http://registrator.url", Name: "orders", Port: 8080,
+		Advertise: AdvertiseConfig{Exclude: []string{"0.0.0.0/0", "::/0"}}}
+
+	client, err := NewClient(cfg, WithLogger(zap.New(core)))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://0.0.0.0:8080/callback", client.store.CallbackURL())
+
+	warnings := logs.FilterLevelExact(zap.WarnLevel).All()
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0].Message, "include [] exclude [0.0.0.0/0, ::/0] ipv6 false")
+		assert.Contains(t, warnings[0].Message, ErrNoAdvertiseAddress.Error())
+	}
+	assert.Zero(t, logs.FilterMessageSnippet("advertising callback address").Len())
+}
@@ -1,16 +1,29 @@
 package client
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
-	"github.com/Danis0n/goreg/internal/goreg/httpprovider"
+	"github.com/Danis0n/goreg/internal/goreg/clock"
 	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/Danis0n/goreg/internal/goreg/tlsprovider"
+	"github.com/Danis0n/goreg/internal/goreg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 type HTTPClient interface {
@@ -18,19 +31,39 @@ type HTTPClient interface {
 }
 
 type Client struct {
-	store       *ClientStore
+	store *ClientStore
+	// services are the stores of ClientConfig.Services.
+	services    []*ClientStore
 	logger      *zap.Logger
 	httpClient  HTTPClient
+	httpServer  *http.Server
+	listener    net.Listener
+	clock       clock.Clock
+	tls         *tlsprovider.Reloader
 	registrator string
+	token       string
+	retry       time.Duration
+	heartbeat   time.Duration
+	cache       *discoveryCache
+	metrics     *Metrics
+	events      EventHandler
+	tracer      trace.Tracer
+	grpc        *GRPCRegistry
+	grpcConn    *grpc.ClientConn
 	errch       chan error
 	closeCh     chan struct{}
 	closeDoneCh chan struct{}
+	closeOnce   sync.Once
+	started     atomic.Bool
 }
 
 type RegisterRequest struct {
-	Callback string `json:"callback"`
-	Name     string `json:"name"`
-	Port     int    `json:"port"`
+	Callback string            `json:"callback"`
+	Name     string            `json:"name"`
+	Port     int               `json:"port"`
+	Address  string            `json:"address,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type RegisterResponse struct {
@@ -42,30 +75,117 @@ const (
 	callback   = "/callback"
 )
 
-func NewClient(cfg ClientConfig) (*Client, error) {
-	stor, err := NewClientStore(cfg)
-	if err != nil {
+func NewClient(cfg ClientConfig, opts ...Option) (*Client, error) {
+	if err := ValidateClientConfig(cfg); err != nil {
 		return nil, err
 	}
 
-	logger, err := zap.NewDevelopment()
+	o, err := newOptions(opts)
 	if err != nil {
 		return nil, err
 	}
 
+	if cfg.Callback == "" {
+		if cfg.Callback, err = advertiseCallback(cfg); err != nil {
+			o.logger.Warn("goreg->[client]: cannot advertise an address for " + cfg.Advertise.describe() + ": " + err.Error() +
+				", the registry will use the source address")
+		} else {
+			o.logger.Info("goreg->[client]: advertising callback address: " + cfg.Callback)
+		}
+	}
+
+	stor := o.store
+	if stor == nil {
+		if stor, err = NewClientStore(cfg, WithLogger(o.logger)); err != nil {
+			return nil, err
+		}
+	}
+
+	retry := cfg.RetryInterval
+	if retry == 0 {
+		retry = DefaultRetryInterval
+	}
+
+	heartbeat := cfg.HeartbeatInterval
+	if heartbeat == 0 {
+		heartbeat = DefaultHeartbeatInterval
+	}
+
+	ttl := cfg.DiscoveryTTL
+	if ttl == 0 {
+		ttl = DefaultDiscoveryTTL
+	}
+
+	var reloader *tlsprovider.Reloader
+	if cfg.TLS.Enabled() {
+		if reloader, err = tlsprovider.NewReloader(cfg.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	httpClient := o.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+		if reloader != nil {
+			httpClient = reloader.HTTPClient()
+		}
+	}
+
+	var grpcConn *grpc.ClientConn
+	conn := o.grpcConn
+	if conn == nil && cfg.GRPCAddress != "" {
+		creds := insecure.NewCredentials()
+		if reloader != nil {
+			creds = reloader.TransportCredentials()
+		}
+
+		if grpcConn, err = grpc.NewClient(cfg.GRPCAddress, grpc.WithTransportCredentials(creds)); err != nil {
+			return nil, err
+		}
+		conn = grpcConn
+	}
+
+	var grpcRegistry *GRPCRegistry
+	if conn != nil {
+		grpcRegistry = NewGRPCRegistry(conn, cfg.Token)
+	}
+
+	services := make([]*ClientStore, 0, len(cfg.Services))
+	for _, svc := range cfg.Services {
+		services = append(services, newServiceStore(cfg, svc, o.logger))
+	}
+
+	registrator := cfg.RegistryAddress()
+	if registrator != strings.TrimSuffix(cfg.Registrator, "/") {
+		o.logger.Warn("goreg->[client]: registrator address {" + cfg.Registrator + "} names the /set route, using {" + registrator + "}")
+	}
+
 	return &Client{
 		store:       stor,
-		logger:      logger,
-		registrator: cfg.Registrator,
+		services:    services,
+		logger:      o.logger,
+		registrator: registrator,
+		token:       cfg.Token,
+		retry:       retry,
+		heartbeat:   heartbeat,
+		cache:       newDiscoveryCache(ttl),
+		metrics:     NewMetrics(),
+		events:      o.events,
+		tracer:      tracing.Tracer(o.tracerProvider),
+		grpc:        grpcRegistry,
+		grpcConn:    grpcConn,
 		errch:       make(chan error),
 		closeCh:     make(chan struct{}),
 		closeDoneCh: make(chan struct{}),
-		httpClient:  &http.Client{},
+		httpClient:  httpClient,
+		listener:    o.listener,
+		clock:       o.clock,
+		tls:         reloader,
 	}, nil
 }
 
-func NewClientWithStart(cfg ClientConfig) (*Client, error) {
-	client, err := NewClient(cfg)
+func NewClientWithStart(cfg ClientConfig, opts ...Option) (*Client, error) {
+	client, err := NewClient(cfg, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -75,32 +195,154 @@ func NewClientWithStart(cfg ClientConfig) (*Client, error) {
 }
 
 func (c *Client) Start() {
-	defer close(c.closeDoneCh)
+	if !c.started.CompareAndSwap(false, true) {
+		return
+	}
 
 	c.StartListener(callback)
 	c.doRegister()
 
 	go func() {
+		defer close(c.closeDoneCh)
+
 		for {
 			select {
 			case <-c.closeCh:
-				c.logger.Info("goreg->[client]: studtown")
+				c.logger.Info("goreg->[client]: shutdown")
 				return
 			case err := <-c.errch:
 				c.logger.Error(err.Error())
+			case <-c.clock.After(c.heartbeat):
+				c.doHeartbeat()
 			}
 		}
 	}()
 }
 
+// Metrics exposes the client Prometheus registry.
+func (c *Client) Metrics() *Metrics {
+	return c.metrics
+}
+
+// Shutdown stops the heartbeats, deregisters every service of the client
+// and stops the listener, so the registry stops routing to this process
+// before it exits. Deregistration is retried while ctx allows.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
+
+	if c.started.Load() {
+		select {
+		case <-c.closeDoneCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var err error
+	for _, s := range c.stores() {
+		err = errors.Join(err, c.deregister(ctx, s))
+	}
+
+	if c.httpServer != nil {
+		err = errors.Join(err, c.httpServer.Shutdown(ctx))
+	}
+	if c.grpcConn != nil {
+		err = errors.Join(err, c.grpcConn.Close())
+	}
+
+	return err
+}
+
+// Stutdown is Shutdown without a deadline.
+//
+// Deprecated: use Shutdown.
 func (c *Client) Stutdown() {
-	c.doUnregister()
-	close(c.closeCh)
-	<-c.closeDoneCh
+	if err := c.Shutdown(context.Background()); err != nil {
+		c.logger.Error("goreg->[client]: shutdown error: " + err.Error())
+	}
 }
 
+// ShutdownOnSignal calls Shutdown, bounded by timeout, on the first SIGTERM
+// or SIGINT, or on signals when given. The returned channel receives the
+// result, after which the process should exit; it receives nil right away
+// when the client is shut down otherwise.
+func (c *Client) ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) <-chan error {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+
+	done := make(chan error, 1)
+	go func() {
+		defer signal.Stop(sigCh)
+
+		select {
+		case sig := <-sigCh:
+			c.logger.Info("goreg->[client]: " + sig.String() + " received, deregistering")
+		case <-c.closeCh:
+			done <- nil
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		done <- c.Shutdown(ctx)
+	}()
+
+	return done
+}
+
+// StartListener serves the callback of the main service at callback and
+// those of ClientConfig.Services at their own routes, all on one listener.
 func (c *Client) StartListener(callback string) {
-	http.HandleFunc(callback, func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.Handle(callback, tracing.Middleware(c.tracer, callback, http.HandlerFunc(c.CallbackHandler)))
+	for _, s := range c.services {
+		mux.Handle(s.Route(), tracing.Middleware(c.tracer, s.Route(), c.callbackHandler(s)))
+	}
+
+	c.httpServer = &http.Server{
+		Addr:    ":" + strconv.Itoa(c.store.Port),
+		Handler: mux,
+	}
+
+	ln := c.listener
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", c.httpServer.Addr); err != nil {
+			c.logger.Error("goreg->[client]: listener error: " + err.Error())
+			return
+		}
+	}
+
+	go func() {
+		var err error
+		if c.tls != nil && c.tls.Certificate() != nil {
+			c.httpServer.TLSConfig = c.tls.ServerTLSConfig()
+			c.logger.Info("goreg->[client]: listener was started with TLS at: " + ln.Addr().String())
+			err = c.httpServer.ServeTLS(ln, "", "")
+		} else {
+			c.logger.Info("goreg->[client]: listener was started at: " + ln.Addr().String())
+			err = c.httpServer.Serve(ln)
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			c.logger.Error("goreg->[client]: listener error: " + err.Error())
+		}
+	}()
+}
+
+func (c *Client) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	c.callbackHandler(c.store)(w, r)
+}
+
+// callbackHandler answers the registry probes of the service of s.
+func (c *Client) callbackHandler(s *ClientStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		if err := server.ValidateHttpMethod(r.Method, http.MethodGet); err != nil {
 			http.Error(w, err.Error(), http.StatusMethodNotAllowed)
 			return
@@ -112,93 +354,221 @@ func (c *Client) StartListener(callback string) {
 			return
 		}
 
-		if err := c.Hash(hash); err != nil {
+		err := matchHash(s, hash)
+		c.metrics.probed(err == nil)
+		c.events.OnProbe(s.Name, err == nil)
+
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusNotFound)
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
-	})
+	}
 }
 
 func (c *Client) Hash(hash string) error {
-	if hash != c.store.Hash {
+	return matchHash(c.store, hash)
+}
+
+func matchHash(s *ClientStore, hash string) error {
+	if hash != s.hash() {
 		return errors.New("goreg->[client]: hash dismatch")
 	}
 	return nil
 }
 
+// stores returns the store of the main service followed by those of
+// ClientConfig.Services.
+func (g *Client) stores() []*ClientStore {
+	return append([]*ClientStore{g.store}, g.services...)
+}
+
+func registerRequest(s *ClientStore) RegisterRequest {
+	return RegisterRequest{
+		Callback: s.CallbackURL(),
+		Name:     s.Name,
+		Port:     s.Port,
+		Tags:     s.Tags,
+		Metadata: s.Metadata,
+	}
+}
+
+// doRegister registers the services of the client that have no hash yet.
+// Several of them are registered in one batch when the registry API allows
+// it, each one on its own otherwise.
 func (g *Client) doRegister() {
-	if g.store.Hash != "" {
+	var pending []*ClientStore
+	for _, s := range g.stores() {
+		if s.hash() == "" {
+			pending = append(pending, s)
+		}
+	}
+
+	if len(pending) == 0 {
 		g.logger.Warn("goreg->[client]: already has hash")
 		return
 	}
 
-	defer close(g.errch)
+	if len(pending) > 1 && g.grpc == nil && g.registerBatch(pending) {
+		return
+	}
 
-	b := &RegisterRequest{
-		Callback: g.store.Callback,
-		Name:     g.store.Name,
-		Port:     g.store.Port,
+	for _, s := range pending {
+		g.register(s)
 	}
+}
 
-	reqBytes := new(bytes.Buffer)
-	if err := json.NewEncoder(reqBytes).Encode(b); err != nil {
-		g.logger.Error("goreg->[client]: request encoding error")
-		g.errch <- err
-		return
+// registerBatch makes one attempt to register every store of pending at
+// once. It reports false when nothing was registered.
+func (g *Client) registerBatch(pending []*ClientStore) bool {
+	ctx, span := g.tracer.Start(context.Background(), "goreg.register.batch",
+		trace.WithAttributes(attribute.Int("goreg.register.services", len(pending))),
+	)
+
+	reqs := make([]RegisterRequest, len(pending))
+	for i, s := range pending {
+		reqs[i] = registerRequest(s)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, g.registrator, reqBytes)
+	g.metrics.attempted(false)
+	responses, err := g.httpRegistry().RegisterAll(ctx, reqs)
+	tracing.End(span, err)
 	if err != nil {
-		g.logger.Error("goreg->[client]: request create error")
-		g.errch <- err
-		return
+		g.logger.Warn("goreg->[client]: batch registration failed, registering services one by one: " + err.Error())
+		return false
 	}
 
+	for i, s := range pending {
+		s.setHash(responses[i].Hash)
+		g.logger.Info("goreg->[client]: service {" + s.Name + "} was registered")
+		g.events.OnRegistered(s.Name, responses[i].Hash)
+	}
+	return true
+}
+
+func (g *Client) register(s *ClientStore) {
+	b := registerRequest(s)
+
+	ctx, span := g.tracer.Start(context.Background(), "goreg.register",
+		trace.WithAttributes(semconv.ServiceName(s.Name)),
+	)
+
+	var lastErr error
 	for i := 0; i < maxRetries; i++ {
-		data, err := httpprovider.Request(req, g.httpClient)
+		if i > 0 {
+			<-g.clock.After(g.retry)
+		}
+		g.metrics.attempted(i > 0)
+
+		response, err := g.registry().Register(ctx, b)
 		if err != nil {
-			g.logger.Error("goreg->[client]: request error")
-			time.Sleep(time.Second)
+			g.logger.Error("goreg->[client]: request error: " + err.Error())
+			lastErr = err
 			continue
 		}
 
-		var response RegisterResponse
-		if err := json.Unmarshal(data, &response); err != nil {
-			g.logger.Error("goreg->[client]: response unmarshal error")
-			time.Sleep(time.Second)
+		if response.Hash == "" {
+			g.logger.Error("goreg->[client]: response has no hash")
+			lastErr = errors.New("goreg->[client]: response has no hash")
 			continue
 		}
 
-		g.store.Hash = response.Hash
-		g.logger.Info("goreg->[client]: service was registered")
+		s.setHash(response.Hash)
+		g.logger.Info("goreg->[client]: service {" + s.Name + "} was registered")
+		g.events.OnRegistered(s.Name, response.Hash)
+		tracing.End(span, nil, attribute.Int("goreg.register.attempts", i+1))
 		return
 	}
 
+	tracing.End(span, lastErr, attribute.Int("goreg.register.attempts", maxRetries))
 	g.logger.Error("goreg->[client]: registration failed after max retries")
+	g.events.OnRegistryUnreachable(lastErr)
 }
 
-func (g *Client) doUnregister() {
-	url := g.registrator + "&name=" + g.store.Name + strconv.Itoa(g.store.Port)
+// doHeartbeat checks that the registry still holds the registration of every
+// service and registers again those it dropped.
+func (g *Client) doHeartbeat() {
+	for _, s := range g.stores() {
+		g.renew(s)
+	}
+}
 
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
-	if err != nil {
-		g.logger.Error("goreg->[client]: request create error")
-		g.errch <- err
+func (g *Client) renew(s *ClientStore) {
+	hash := s.hash()
+	if hash == "" {
+		g.register(s)
 		return
 	}
 
+	ctx, span := g.tracer.Start(context.Background(), "goreg.heartbeat",
+		trace.WithAttributes(semconv.ServiceName(s.Name)),
+	)
+
+	start := g.clock.Now()
+	err := g.registry().Renew(ctx, s.Name, hash)
+	elapsed := g.clock.Now().Sub(start).Seconds()
+	tracing.End(span, err)
+
+	switch {
+	case errors.Is(err, ErrNotRegistered):
+		g.metrics.heartbeat("deregistered", elapsed)
+		g.logger.Warn("goreg->[client]: service {" + s.Name + "} was deregistered by the registry")
+		s.setHash("")
+		g.events.OnDeregistered(s.Name)
+		g.register(s)
+	case err != nil:
+		g.metrics.heartbeat("error", elapsed)
+		g.logger.Error("goreg->[client]: heartbeat error: " + err.Error())
+		g.events.OnRegistryUnreachable(err)
+	default:
+		g.metrics.heartbeat("ok", elapsed)
+	}
+}
+
+func (g *Client) registry() transport {
+	if g.grpc != nil {
+		return g.grpc
+	}
+	return g.httpRegistry()
+}
+
+func (g *Client) httpRegistry() *Registry {
+	return &Registry{
+		address:    strings.TrimSuffix(g.registrator, "/"),
+		token:      g.token,
+		httpClient: g.httpClient,
+	}
+}
+
+// deregister removes the registration of s, retrying while ctx allows. A
+// registration the registry already dropped counts as removed.
+func (g *Client) deregister(ctx context.Context, s *ClientStore) error {
+	hash := s.hash()
+	if hash == "" {
+		return nil
+	}
+
+	var err error
 	for i := 0; i < maxRetries; i++ {
-		_, err := httpprovider.Request(req, g.httpClient)
-		if err != nil {
-			g.logger.Error("goreg->[client]: request error")
-			time.Sleep(time.Second)
-			continue
+		if i > 0 {
+			select {
+			case <-g.clock.After(g.retry):
+			case <-ctx.Done():
+				return errors.Join(err, ctx.Err())
+			}
 		}
 
-		g.logger.Info("goreg->[client]: service was unregistered")
-		return
+		err = g.registry().DeregisterInstance(ctx, s.Name, hash)
+		if err == nil || errors.Is(err, ErrNotRegistered) {
+			s.setHash("")
+			g.logger.Info("goreg->[client]: service {" + s.Name + "} was deregistered")
+			g.events.OnDeregistered(s.Name)
+			return nil
+		}
+		g.logger.Error("goreg->[client]: deregistration error: " + err.Error())
 	}
 
-	g.logger.Error("goreg->[client]: unregistration failed after max retries")
+	g.logger.Error("goreg->[client]: deregistration failed after max retries")
+	return err
 }
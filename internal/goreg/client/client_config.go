@@ -2,19 +2,73 @@ package client
 
 import (
 	"errors"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Danis0n/goreg/internal/goreg/tlsprovider"
+	"github.com/Danis0n/goreg/internal/goreg/validation"
 	"github.com/google/uuid"
 )
 
 type ClientConfig struct {
-	Registrator string `yaml:"address"`
-	Callback    string `yaml:"callback_address"`
-	Name        string `yaml:"name"`
-	Port        int    `yaml:"port"`
+	// Registrator is the base address of the registry, e.g. http://registry:8079.
+	// The registration URL older configs set, http://registry:8079/set, is
+	// still accepted; see RegistryAddress.
+	Registrator string `yaml:"address" json:"address"`
+	// Callback is the base address the registry probes. When empty it is
+	// built from the listener port and the address picked by Advertise.
+	Callback  string             `yaml:"callback_address" json:"callback_address"`
+	Advertise AdvertiseConfig    `yaml:"advertise" json:"advertise"`
+	Name      string             `yaml:"name" json:"name"`
+	Port      int                `yaml:"port" json:"port"`
+	Token     string             `yaml:"token" json:"token"`
+	TLS       tlsprovider.Config `yaml:"tls" json:"tls"`
+	// RetryInterval is the pause between registry request attempts.
+	RetryInterval time.Duration `yaml:"retry_interval" json:"retry_interval"`
+	// HeartbeatInterval is how often the client verifies its registration.
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" json:"heartbeat_interval"`
+	// DiscoveryTTL is how long Discover caches a looked up service.
+	DiscoveryTTL time.Duration `yaml:"discovery_ttl" json:"discovery_ttl"`
+	// Tags and Metadata are published with the registration, e.g. as
+	// Prometheus service discovery labels.
+	Tags     []string          `yaml:"tags" json:"tags"`
+	Metadata map[string]string `yaml:"metadata" json:"metadata"`
+	// GRPCAddress switches registry calls to the gRPC API at host:port.
+	// Registrator is still required for the HTTP only tooling.
+	GRPCAddress string `yaml:"grpc_address" json:"grpc_address"`
+	// Services are further services of the same process, e.g. a metrics
+	// endpoint. Each one has its own registration and heartbeat and is probed
+	// through the shared listener at /callback/<name>.
+	Services []ServiceConfig `yaml:"services" json:"services"`
+}
+
+// ServiceConfig is one of ClientConfig.Services.
+type ServiceConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Port is where the service is reached, not the listener probed by the
+	// registry.
+	Port     int               `yaml:"port" json:"port"`
+	Tags     []string          `yaml:"tags" json:"tags"`
+	Metadata map[string]string `yaml:"metadata" json:"metadata"`
 }
 
 const (
 	DefalutCallbackAddress = "callback"
+
+	legacyRegisterPath = "/set"
+
+	DefaultRetryInterval = time.Second
+	minRetryInterval     = 10 * time.Millisecond
+	maxRetryInterval     = time.Minute
+
+	DefaultHeartbeatInterval = 30 * time.Second
+	minHeartbeatInterval     = time.Second
+	maxHeartbeatInterval     = 10 * time.Minute
+
+	DefaultDiscoveryTTL = 10 * time.Second
+	minDiscoveryTTL     = time.Millisecond
+	maxDiscoveryTTL     = time.Hour
 )
 
 func NewClientConfigWithDefaults(
@@ -22,20 +76,21 @@ func NewClientConfigWithDefaults(
 	callbackAddress string,
 	port int,
 ) (ClientConfig, error) {
-	if err := validateClientSettings(registratorAddress, callbackAddress, port); err != nil {
+	name := uuid.New().String()
+	if err := validateClientSettings(registratorAddress, callbackAddress, port, name); err != nil {
 		return ClientConfig{}, err
 	}
 
 	return ClientConfig{
 		Registrator: registratorAddress,
 		Callback:    callbackAddress,
-		Name:        uuid.New().String(),
+		Name:        name,
 		Port:        port,
 	}, nil
 }
 
 func NewClientConfigWithName(registrator string, callbackAddress string, port int, name string) (ClientConfig, error) {
-	if err := validateClientSettings(registrator, callbackAddress, port); err != nil {
+	if err := validateClientSettings(registrator, callbackAddress, port, name); err != nil {
 		return ClientConfig{}, err
 	}
 
@@ -47,25 +102,61 @@ func NewClientConfigWithName(registrator string, callbackAddress string, port in
 	}, nil
 }
 
-func ValidateClientConfig(cfg ClientConfig) error {
-	if err := validateClientSettings(cfg.Registrator, cfg.Callback, cfg.Port); err != nil {
-		return err
-	}
-	return nil
+// RegistryAddress is Registrator without the /set suffix it had when it named
+// the registration URL rather than the registry.
+func (cfg ClientConfig) RegistryAddress() string {
+	address := strings.TrimSuffix(cfg.Registrator, "/")
+	return strings.TrimSuffix(address, legacyRegisterPath)
 }
 
-func validateClientSettings(registrator string, callbackAddress string, port int) error {
-	if callbackAddress == "" {
-		return errors.New("callbackAddress invalid")
+func ValidateClientConfig(cfg ClientConfig) error {
+	var callback error
+	if cfg.Callback != "" {
+		callback = validation.URL("callback_address", cfg.Callback)
 	}
 
-	if registrator == "" {
-		return errors.New("registrator invalid")
-	}
+	return errors.Join(
+		callback,
+		validation.URL("address", cfg.Registrator),
+		validation.Port("port", cfg.Port),
+		validation.ServiceName("name", cfg.Name),
+		validateAdvertiseConfig(cfg.Advertise),
+		validation.Duration("retry_interval", cfg.RetryInterval, minRetryInterval, maxRetryInterval),
+		validation.Duration("heartbeat_interval", cfg.HeartbeatInterval, minHeartbeatInterval, maxHeartbeatInterval),
+		validation.Duration("discovery_ttl", cfg.DiscoveryTTL, minDiscoveryTTL, maxDiscoveryTTL),
+		tlsprovider.ValidateConfig(cfg.TLS),
+		validateServices(cfg.Name, cfg.Services),
+	)
+}
+
+func validateServices(name string, services []ServiceConfig) error {
+	var errs []error
+	names := map[string]bool{name: true}
+	for i, svc := range services {
+		field := "services[" + strconv.Itoa(i) + "]"
+		errs = append(errs,
+			validation.ServiceName(field+".name", svc.Name),
+			validation.Port(field+".port", svc.Port),
+		)
 
-	if port <= 0 {
-		return errors.New("port invalid")
+		if names[svc.Name] {
+			errs = append(errs, &validation.FieldError{
+				Field:  field + ".name",
+				Value:  svc.Name,
+				Err:    validation.ErrInvalidName,
+				Detail: "is used by another service of the client",
+			})
+		}
+		names[svc.Name] = true
 	}
+	return errors.Join(errs...)
+}
 
-	return nil
+func validateClientSettings(registrator string, callbackAddress string, port int, name string) error {
+	return errors.Join(
+		validation.URL("callbackAddress", callbackAddress),
+		validation.URL("registrator", registrator),
+		validation.Port("port", port),
+		validation.ServiceName("name", name),
+	)
 }
@@ -1,8 +1,11 @@
 package client
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/Danis0n/goreg/internal/goreg/validation"
 	"github.com/google/uuid"
 )
 
@@ -102,3 +105,95 @@ func TestValidateClientConfig_Invalid(t *testing.T) {
 		t.Fatal("Expected error for invalid config, got nil")
 	}
 }
+
+func TestValidateClientConfig_FieldErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   ClientConfig
+		field string
+	}{
+		{
+			name:  "Port out of range",
+			cfg:   ClientConfig{Registrator: "http://registrator.url", Callback: "http://callback.url", Name: "test-client", Port: 99999},
+			field: "port",
+		},
+		{
+			name:  "Registrator is not a url",
+			cfg:   ClientConfig{Registrator: "abc", Callback: "http://callback.url", Name: "test-client", Port: 8080},
+			field: "address",
+		},
+		{
+			name:  "Callback is not a url",
+			cfg:   ClientConfig{Registrator: "http://registrator.url", Callback: "abc", Name: "test-client", Port: 8080},
+			field: "callback_address",
+		},
+		{
+			name:  "Name with slash",
+			cfg:   ClientConfig{Registrator: "http://registrator.url", Callback: "http://callback.url", Name: "test/client", Port: 8080},
+			field: "name",
+		},
+		{
+			name:  "Retry interval out of bounds",
+			cfg:   ClientConfig{Registrator: "http://registrator.url", Callback: "http://callback.url", Name: "test-client", Port: 8080, RetryInterval: time.Hour},
+			field: "retry_interval",
+		},
+		{
+			name: "Service name used twice",
+			cfg: ClientConfig{Registrator: "http://registrator.url", Callback: "http://callback.url", Name: "test-client", Port: 8080,
+				Services: []ServiceConfig{{Name: "test-client", Port: 9090}}},
+			field: "services[0].name",
+		},
+		{
+			name: "Service without port",
+			cfg: ClientConfig{Registrator: "http://registrator.url", Callback: "http://callback.url", Name: "test-client", Port: 8080,
+				Services: []ServiceConfig{{Name: "test-metrics"}}},
+			field: "services[0].port",
+		},
+		{
+			name: "Advertise CIDR",
+			cfg: ClientConfig{Registrator: "http://registrator.url", Name: "test-client", Port: 8080,
+				Advertise: AdvertiseConfig{Exclude: []string{"172.17.0.1"}}},
+			field: "advertise.exclude[0]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateClientConfig(tt.cfg)
+
+			var fieldErr *validation.FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("Expected FieldError, got %v", err)
+			}
+
+			if fieldErr.Field != tt.field {
+				t.Errorf("Expected field %s, got %s", tt.field, fieldErr.Field)
+			}
+		})
+	}
+}
+
+func TestClientConfig_RegistryAddress(t *testing.T) {
+	tests := []struct {
+		name        string
+		registrator string
+		want        string
+	}{
+		{"Base address", "http://registry:8079", "http://registry:8079"},
+		{"Trailing slash", "http://registry:8079/", "http://registry:8079"},
+		{"Legacy set route", "http://registry:8079/set", "http://registry:8079"},
+		{"Legacy set route with slash", "http://registry:8079/set/", "http://registry:8079"},
+		{"Path prefix", "http://gateway/goreg", "http://gateway/goreg"},
+		{"Path prefix with set route", "http://gateway/goreg/set", "http://gateway/goreg"},
+		{"Name ending in set", "http://registry-set:8079", "http://registry-set:8079"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := ClientConfig{Registrator: tt.registrator}
+			if got := cfg.RegistryAddress(); got != tt.want {
+				t.Errorf("RegistryAddress() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
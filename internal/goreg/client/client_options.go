@@ -0,0 +1,110 @@
+package client
+
+import (
+	"net"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+type Option func(*options)
+
+type options struct {
+	logger     *zap.Logger
+	httpClient HTTPClient
+	clock      clock.Clock
+	store      *ClientStore
+	listener   net.Listener
+	events     EventHandler
+	grpcConn   grpc.ClientConnInterface
+
+	tracerProvider trace.TracerProvider
+}
+
+// WithLogger replaces the development logger created by NewClient and
+// NewClientStore.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithHTTPClient sets the client used for registry requests. It takes
+// precedence over the one built from ClientConfig.TLS.
+func WithHTTPClient(client HTTPClient) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithStore makes the client use an existing store instead of building one
+// from the config.
+func WithStore(store *ClientStore) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithListener makes the callback listener serve on l instead of listening on
+// ClientConfig.Port.
+func WithListener(l net.Listener) Option {
+	return func(o *options) {
+		o.listener = l
+	}
+}
+
+// WithEventHandler registers h for registration lifecycle events.
+func WithEventHandler(h EventHandler) Option {
+	return func(o *options) {
+		o.events = h
+	}
+}
+
+// WithGRPCConn makes the client talk to the registry over gRPC on conn. It
+// takes precedence over ClientConfig.GRPCAddress.
+func WithGRPCConn(conn grpc.ClientConnInterface) Option {
+	return func(o *options) {
+		o.grpcConn = conn
+	}
+}
+
+// WithTracerProvider enables OpenTelemetry spans for registration, heartbeats
+// and callback probes. Tracing is a no-op without it.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.logger == nil {
+		logger, err := zap.NewDevelopment()
+		if err != nil {
+			return nil, err
+		}
+		o.logger = logger
+	}
+
+	if o.clock == nil {
+		o.clock = clock.Real()
+	}
+
+	if o.events == nil {
+		o.events = NopEventHandler{}
+	}
+
+	return o, nil
+}
@@ -1,32 +1,85 @@
 package client
 
 import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
 	"go.uber.org/zap"
 )
 
 type ClientStore struct {
+	mu       sync.RWMutex
 	logger   *zap.Logger
 	Hash     string
 	Callback string
 	Name     string
 	Port     int
+	Tags     []string
+	Metadata map[string]string
+	// route is the listener path probed for this service, callback when
+	// empty.
+	route string
 }
 
-func NewClientStore(cfg ClientConfig) (*ClientStore, error) {
+func NewClientStore(cfg ClientConfig, opts ...Option) (*ClientStore, error) {
 	if err := ValidateClientConfig(cfg); err != nil {
 		return nil, err
 	}
 
-	logger, err := zap.NewDevelopment()
+	o, err := newOptions(opts)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ClientStore{
-		logger:   logger,
-		Callback: cfg.Callback + callback,
+		logger:   o.logger,
+		Callback: cfg.Callback,
 		Name:     cfg.Name,
 		Port:     cfg.Port,
+		Tags:     slices.Clone(cfg.Tags),
+		Metadata: maps.Clone(cfg.Metadata),
 		Hash:     "",
 	}, nil
 }
+
+// newServiceStore is the store of one of cfg.Services, probed at
+// /callback/<name> on the listener of the main service.
+func newServiceStore(cfg ClientConfig, svc ServiceConfig, logger *zap.Logger) *ClientStore {
+	return &ClientStore{
+		logger:   logger,
+		Callback: cfg.Callback,
+		Name:     svc.Name,
+		Port:     svc.Port,
+		Tags:     slices.Clone(svc.Tags),
+		Metadata: maps.Clone(svc.Metadata),
+		route:    callback + "/" + svc.Name,
+	}
+}
+
+// CallbackURL is the address the registry probes: the configured callback
+// address plus the path served by the client listener.
+func (s *ClientStore) CallbackURL() string {
+	return strings.TrimSuffix(s.Callback, "/") + s.Route()
+}
+
+// Route is the listener path the registry probes for this service.
+func (s *ClientStore) Route() string {
+	if s.route == "" {
+		return callback
+	}
+	return s.route
+}
+
+func (s *ClientStore) hash() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Hash
+}
+
+func (s *ClientStore) setHash(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Hash = hash
+}
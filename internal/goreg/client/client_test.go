@@ -2,12 +2,21 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/Danis0n/goreg/internal/goreg/server"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 type MockHTTPClient struct {
@@ -72,3 +81,254 @@ func TestClientDoRegister_Failure(t *testing.T) {
 
 	assert.Empty(t, client.store.Hash)
 }
+
+func TestClientCallbackHandler(t *testing.T) {
+	cfg := ClientConfig{
+		Registrator: "http://registrator.url",
+		Callback:    "http://callback.url",
+		Name:        "test-client",
+		Port:        8080,
+	}
+
+	client, err := NewClient(cfg)
+	assert.NoError(t, err)
+	client.store.Hash = "test-hash"
+
+	rr := httptest.NewRecorder()
+	client.CallbackHandler(rr, httptest.NewRequest(http.MethodGet, "/callback?hash=test-hash", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	client.CallbackHandler(rr, httptest.NewRequest(http.MethodGet, "/callback?hash=other", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestNewClient_Options(t *testing.T) {
+	cfg := ClientConfig{
+		Registrator: "http://registrator.url",
+		Callback:    "http://callback.url",
+		Name:        "test-client",
+		Port:        8080,
+	}
+
+	logger := zap.NewNop()
+	store, err := NewClientStore(cfg, WithLogger(logger))
+	assert.NoError(t, err)
+
+	var registered *http.Request
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			registered = req
+			respBytes, _ := json.Marshal(RegisterResponse{Hash: "test-hash"})
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewBuffer(respBytes)),
+			}, nil
+		},
+	}
+
+	client, err := NewClient(cfg, WithLogger(logger), WithStore(store), WithHTTPClient(mockClient))
+	assert.NoError(t, err)
+	assert.Same(t, logger, client.logger)
+	assert.Same(t, store, client.store)
+
+	client.doRegister()
+
+	assert.NotNil(t, registered)
+	assert.Equal(t, "test-hash", store.Hash)
+
+	var body RegisterRequest
+	assert.NoError(t, json.NewDecoder(registered.Body).Decode(&body))
+	assert.Equal(t, "http://callback.url/callback", body.Callback)
+}
+
+func TestClient_MultipleServices(t *testing.T) {
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079}, server.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	cfg := ClientConfig{
+		Registrator: ts.URL,
+		Callback:    "http://orders:8080",
+		Name:        "orders",
+		Port:        8080,
+		Services:    []ServiceConfig{{Name: "orders-metrics", Port: 9090, Tags: []string{"metrics"}}},
+	}
+
+	client, err := NewClient(cfg, WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	client.doRegister()
+
+	registry, err := NewRegistry(ts.URL, "")
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	metrics, err := registry.Get(ctx, "orders-metrics")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://orders:8080/callback/orders-metrics", metrics.Callback)
+	assert.Equal(t, 9090, metrics.Port)
+	assert.Equal(t, client.services[0].hash(), metrics.Hash)
+	assert.NotEqual(t, client.store.hash(), metrics.Hash)
+
+	rr := httptest.NewRecorder()
+	client.callbackHandler(client.services[0])(rr, httptest.NewRequest(http.MethodGet, "/callback/orders-metrics?hash="+metrics.Hash, nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	client.CallbackHandler(rr, httptest.NewRequest(http.MethodGet, "/callback?hash="+metrics.Hash, nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	assert.NoError(t, registry.Deregister(ctx, "orders-metrics"))
+	client.doHeartbeat()
+
+	renewed, err := registry.Get(ctx, "orders-metrics")
+	assert.NoError(t, err)
+	assert.NotEqual(t, metrics.Hash, renewed.Hash)
+	assert.Equal(t, client.services[0].hash(), renewed.Hash)
+}
+
+func TestClient_LegacyRegistrator(t *testing.T) {
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079}, server.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	cfg := ClientConfig{
+		Registrator: ts.URL + "/set",
+		Callback:    "http://orders:8080",
+		Name:        "orders",
+		Port:        8080,
+	}
+
+	client, err := NewClient(cfg, WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	client.doRegister()
+
+	registry, err := NewRegistry(ts.URL, "")
+	assert.NoError(t, err)
+
+	service, err := registry.Get(context.Background(), "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, client.store.hash(), service.Hash)
+}
+
+func TestClient_HeartbeatRegisterOnlyToken(t *testing.T) {
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079, ACL: server.ACLConfig{
+		Enabled:       true,
+		DefaultPolicy: server.ACLPolicyDeny,
+		Policies: []server.ACLPolicy{
+			{Name: "orders", Rules: []server.ACLRule{{Service: "orders", Capabilities: []server.Capability{server.CapabilityRegister}}}},
+			{Name: "read-only", Rules: []server.ACLRule{{Service: "*", Capabilities: []server.Capability{server.CapabilityRead}}}},
+		},
+		Tokens: []server.ACLToken{
+			{ID: "orders", Secret: "orders-secret", Policies: []string{"orders"}},
+			{ID: "reader", Secret: "reader-secret", Policies: []string{"read-only"}},
+		},
+	}}, server.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	events := &recordingEvents{}
+	cfg := ClientConfig{Registrator: ts.URL, Callback: "http://orders:8080", Name: "orders", Port: 8080, Token: "orders-secret"}
+	client, err := NewClient(cfg, WithLogger(zap.NewNop()), WithEventHandler(events))
+	assert.NoError(t, err)
+
+	client.doRegister()
+	hash := client.store.hash()
+	assert.NotEmpty(t, hash)
+
+	client.doHeartbeat()
+	assert.Empty(t, events.unreachable)
+	assert.Empty(t, events.deregistered)
+	assert.Equal(t, hash, client.store.hash())
+
+	reader, err := NewRegistry(ts.URL, "reader-secret")
+	assert.NoError(t, err)
+	assert.Error(t, reader.Renew(context.Background(), "orders", hash), "renew needs register, not read")
+}
+
+func TestClient_Shutdown(t *testing.T) {
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079}, server.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	cfg := ClientConfig{
+		Registrator: ts.URL,
+		Callback:    "http://" + ln.Addr().String(),
+		Name:        "orders",
+		Port:        8080,
+		Services:    []ServiceConfig{{Name: "orders-metrics", Port: 9090}},
+	}
+
+	events := &recordingEvents{}
+	client, err := NewClient(cfg, WithLogger(zap.NewNop()), WithListener(ln), WithEventHandler(events))
+	assert.NoError(t, err)
+	client.Start()
+
+	registry, err := NewRegistry(ts.URL, "")
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	services, err := registry.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+
+	// A newer registration of the same name is not ours to remove.
+	assert.NoError(t, registry.Deregister(ctx, "orders-metrics"))
+	newer, err := registry.Register(ctx, RegisterRequest{Name: "orders-metrics", Callback: "http://other:9090/callback"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.Shutdown(ctx))
+	assert.NoError(t, client.Shutdown(ctx), "a second shutdown is a no-op")
+	assert.ElementsMatch(t, []string{"orders", "orders-metrics"}, events.deregistered)
+
+	services, err = registry.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, newer.Hash, services[0].Hash)
+
+	_, err = http.Get(cfg.Callback + "/callback?hash=x")
+	assert.Error(t, err, "listener still serves after shutdown")
+
+	select {
+	case err := <-client.ShutdownOnSignal(time.Second):
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("ShutdownOnSignal did not return for a client that is already shut down")
+	}
+}
+
+func TestClient_ShutdownOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to the own process on windows")
+	}
+
+	client, err := NewClient(ClientConfig{Registrator: "http://registrator.url", Callback: "http://callback.url", Name: "orders", Port: 8080},
+		WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+
+	done := client.ShutdownOnSignal(time.Second, syscall.SIGHUP)
+
+	process, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, process.Signal(syscall.SIGHUP))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("client was not shut down on the signal")
+	}
+
+	select {
+	case <-client.closeCh:
+	default:
+		t.Error("heartbeats were not stopped")
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/openapitest"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestRegistryContract checks every request the client sends, and the
+// responses it relies on, against the published OpenAPI document.
+func TestRegistryContract(t *testing.T) {
+	spec := openapitest.Load(t)
+
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079}, server.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(spec.Middleware(t, srv.Handler()))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	registry, err := NewRegistry(ts.URL, "secret")
+	assert.NoError(t, err)
+
+	response, err := registry.Register(ctx, RegisterRequest{
+		Name:     "orders",
+		Callback: "http://orders:8080/callback",
+		Port:     8080,
+		Tags:     []string{"v1"},
+		Metadata: map[string]string{"team": "checkout"},
+	})
+	assert.NoError(t, err)
+
+	orders, err := registry.Get(ctx, "orders")
+	assert.NoError(t, err)
+	_, err = registry.Update(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:9090/callback"}, orders.ModifyIndex)
+	assert.NoError(t, err)
+	_, err = registry.Update(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:9090/callback"}, orders.ModifyIndex)
+	assert.Error(t, err)
+	_, err = registry.Get(ctx, "missing")
+	assert.Error(t, err)
+
+	_, err = registry.List(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, registry.Renew(ctx, "orders", response.Hash))
+	_, err = registry.Health(ctx)
+	assert.NoError(t, err)
+
+	watchCtx, stop := context.WithCancel(ctx)
+	<-registry.Watch(watchCtx, time.Millisecond, nil)
+	stop()
+
+	assert.NoError(t, registry.Deregister(ctx, "orders"))
+
+	responses, err := registry.RegisterAll(ctx, []RegisterRequest{
+		{Name: "billing", Callback: "http://billing:8080/callback", Port: 8080},
+		{Name: "billing-metrics", Callback: "http://billing:8080/callback/billing-metrics", Port: 9090, Tags: []string{"metrics"}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, responses, 2)
+
+	_, err = registry.Batch(ctx, []server.BatchOp{
+		{Verb: server.BatchRenew, Service: server.Service{Name: "billing", Hash: responses[0].Hash}},
+		{Verb: server.BatchDeregister, Service: server.Service{Name: "billing-metrics", Hash: responses[1].Hash}},
+	})
+	assert.NoError(t, err)
+	_, err = registry.Batch(ctx, []server.BatchOp{{Verb: server.BatchRenew, Service: server.Service{Name: "missing", Hash: "stale"}}})
+	assert.ErrorIs(t, err, ErrBatchRejected)
+
+	assert.ErrorIs(t, registry.DeregisterInstance(ctx, "billing", "stale"), ErrNotRegistered)
+	assert.NoError(t, registry.DeregisterInstance(ctx, "billing", responses[0].Hash))
+
+	client, err := NewClient(ClientConfig{
+		Registrator: ts.URL,
+		Callback:    "http://callback.url",
+		Name:        "test-client",
+		Port:        8080,
+	}, WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+
+	client.doRegister()
+	client.doHeartbeat()
+	_, err = client.Discover(ctx, "test-client")
+	assert.NoError(t, err)
+}
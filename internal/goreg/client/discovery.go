@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/server"
+)
+
+type discoveryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]discoveryEntry
+}
+
+type discoveryEntry struct {
+	service server.Service
+	expires time.Time
+}
+
+func newDiscoveryCache(ttl time.Duration) *discoveryCache {
+	return &discoveryCache{
+		ttl:     ttl,
+		entries: make(map[string]discoveryEntry),
+	}
+}
+
+func (d *discoveryCache) get(name string, now time.Time) (*server.Service, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries[name]
+	if !ok || !now.Before(entry.expires) {
+		delete(d.entries, name)
+		return nil, false
+	}
+
+	service := entry.service
+	return &service, true
+}
+
+func (d *discoveryCache) put(service *server.Service, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries[service.Name] = discoveryEntry{
+		service: *service,
+		expires: now.Add(d.ttl),
+	}
+}
+
+// Discover looks up another service in the registry. Results are cached for
+// ClientConfig.DiscoveryTTL.
+func (c *Client) Discover(ctx context.Context, name string) (*server.Service, error) {
+	if service, ok := c.cache.get(name, c.clock.Now()); ok {
+		c.metrics.cacheLookup(true)
+		return service, nil
+	}
+	c.metrics.cacheLookup(false)
+
+	service, err := c.registry().Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.put(service, c.clock.Now())
+	return service, nil
+}
+
+// Watch streams the full service list whenever it changes. Over gRPC changes
+// are pushed by the registry; over HTTP each list query blocks on the index of
+// the previous one for up to ClientConfig.DiscoveryTTL, bounded by the HTTP
+// client timeout, and registries without blocking queries are polled as often.
+func (c *Client) Watch(ctx context.Context, onError func(error)) <-chan []*server.Service {
+	if c.grpc != nil {
+		return c.grpc.Watch(ctx, onError)
+	}
+	return c.httpRegistry().Watch(ctx, c.cache.ttl, onError)
+}
@@ -0,0 +1,27 @@
+package client
+
+// EventHandler is notified about the registration lifecycle of a Client.
+// Callbacks run on the client goroutines and must not block.
+type EventHandler interface {
+	// OnRegistered is called after the registry accepted the service.
+	OnRegistered(name, hash string)
+	// OnDeregistered is called when a heartbeat finds that the registry no
+	// longer knows the service, before it is registered again, and when
+	// Shutdown removed the registration.
+	OnDeregistered(name string)
+	// OnProbe is called for every callback probe; ok reports whether the
+	// probe carried the expected hash.
+	OnProbe(name string, ok bool)
+	// OnRegistryUnreachable is called when registration gives up or a
+	// heartbeat cannot reach the registry.
+	OnRegistryUnreachable(err error)
+}
+
+// NopEventHandler ignores every event. Embed it to implement only the
+// callbacks you need.
+type NopEventHandler struct{}
+
+func (NopEventHandler) OnRegistered(name, hash string)  {}
+func (NopEventHandler) OnDeregistered(name string)      {}
+func (NopEventHandler) OnProbe(name string, ok bool)    {}
+func (NopEventHandler) OnRegistryUnreachable(err error) {}
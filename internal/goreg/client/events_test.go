@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type recordingEvents struct {
+	NopEventHandler
+	registered   []string
+	deregistered []string
+	probes       []bool
+	unreachable  []error
+}
+
+func (r *recordingEvents) OnRegistered(name, hash string) { r.registered = append(r.registered, hash) }
+func (r *recordingEvents) OnDeregistered(name string)     { r.deregistered = append(r.deregistered, name) }
+func (r *recordingEvents) OnProbe(name string, ok bool)   { r.probes = append(r.probes, ok) }
+func (r *recordingEvents) OnRegistryUnreachable(err error) {
+	r.unreachable = append(r.unreachable, err)
+}
+
+func jsonResponse(status int, v any) *http.Response {
+	body, _ := json.Marshal(v)
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(bytes.NewBuffer(body)),
+	}
+}
+
+func newTestClient(t *testing.T, events EventHandler, do func(req *http.Request) (*http.Response, error)) *Client {
+	t.Helper()
+
+	cfg := ClientConfig{
+		Registrator: "http://registrator.url",
+		Callback:    "http://callback.url",
+		Name:        "test-client",
+		Port:        8080,
+	}
+
+	client, err := NewClient(cfg,
+		WithLogger(zap.NewNop()),
+		WithHTTPClient(&MockHTTPClient{DoFunc: do}),
+		WithEventHandler(events),
+		WithClock(clock.NewManual(time.Now())),
+	)
+	assert.NoError(t, err)
+	return client
+}
+
+func TestClientEvents_RegisterAndProbe(t *testing.T) {
+	events := &recordingEvents{}
+	client := newTestClient(t, events, func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "/v1/services", req.URL.Path)
+		return jsonResponse(http.StatusCreated, RegisterResponse{Hash: "test-hash"}), nil
+	})
+
+	client.doRegister()
+	assert.Equal(t, []string{"test-hash"}, events.registered)
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.metrics.registrationAttempts))
+	assert.Equal(t, 0.0, testutil.ToFloat64(client.metrics.registrationRetries))
+
+	rr := httptest.NewRecorder()
+	client.CallbackHandler(rr, httptest.NewRequest(http.MethodGet, "/callback?hash=test-hash", nil))
+	rr = httptest.NewRecorder()
+	client.CallbackHandler(rr, httptest.NewRequest(http.MethodGet, "/callback?hash=other", nil))
+
+	assert.Equal(t, []bool{true, false}, events.probes)
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.metrics.probes.WithLabelValues("ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.metrics.probes.WithLabelValues("mismatch")))
+}
+
+func TestClientEvents_HeartbeatDeregistered(t *testing.T) {
+	events := &recordingEvents{}
+	registrations := 0
+	client := newTestClient(t, events, func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/v1/services" {
+			registrations++
+			return jsonResponse(http.StatusCreated, RegisterResponse{Hash: "new-hash"}), nil
+		}
+		return jsonResponse(http.StatusNotFound, nil), nil
+	})
+	client.store.Hash = "old-hash"
+
+	client.doHeartbeat()
+
+	assert.Equal(t, []string{"test-client"}, events.deregistered)
+	assert.Equal(t, 1, registrations)
+	assert.Equal(t, "new-hash", client.store.Hash)
+	assert.Equal(t, 1, testutil.CollectAndCount(client.metrics.heartbeatDuration))
+}
+
+func TestClientEvents_HeartbeatUnreachable(t *testing.T) {
+	events := &recordingEvents{}
+	client := newTestClient(t, events, func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	client.store.Hash = "test-hash"
+
+	client.doHeartbeat()
+
+	assert.Len(t, events.unreachable, 1)
+	assert.Empty(t, events.deregistered)
+	assert.Equal(t, "test-hash", client.store.Hash)
+}
+
+func TestClientDiscover_Cache(t *testing.T) {
+	lookups := 0
+	client := newTestClient(t, nil, func(req *http.Request) (*http.Response, error) {
+		lookups++
+		return jsonResponse(http.StatusOK, server.Service{Name: "orders", Callback: "http://orders:8080/callback"}), nil
+	})
+	manual := client.clock.(*clock.Manual)
+
+	for i := 0; i < 3; i++ {
+		service, err := client.Discover(context.Background(), "orders")
+		assert.NoError(t, err)
+		assert.Equal(t, "orders", service.Name)
+	}
+
+	assert.Equal(t, 1, lookups)
+	assert.Equal(t, 2.0, testutil.ToFloat64(client.metrics.cacheHits))
+	assert.Equal(t, 1.0, testutil.ToFloat64(client.metrics.cacheMisses))
+
+	manual.Advance(DefaultDiscoveryTTL)
+	_, err := client.Discover(context.Background(), "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, lookups)
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/registrypb"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// transport is the part of the registry API the client relies on. Registry
+// implements it over HTTP and GRPCRegistry over gRPC.
+type transport interface {
+	Register(ctx context.Context, req RegisterRequest) (RegisterResponse, error)
+	Get(ctx context.Context, name string) (*server.Service, error)
+	Deregister(ctx context.Context, name string) error
+	DeregisterInstance(ctx context.Context, name, hash string) error
+	Renew(ctx context.Context, name, hash string) error
+}
+
+// ErrNotRegistered is returned by Renew once the registry dropped the
+// registration.
+var ErrNotRegistered = errors.New("goreg->[client]: service is not registered")
+
+// GRPCRegistry talks to the gRPC registry API.
+type GRPCRegistry struct {
+	client registrypb.RegistryClient
+	token  string
+}
+
+func NewGRPCRegistry(conn grpc.ClientConnInterface, token string) *GRPCRegistry {
+	return &GRPCRegistry{
+		client: registrypb.NewRegistryClient(conn),
+		token:  token,
+	}
+}
+
+func (r *GRPCRegistry) List(ctx context.Context) ([]*server.Service, error) {
+	response, err := r.client.List(r.context(ctx), &registrypb.ListRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return fromProtoServices(response.GetServices()), nil
+}
+
+func (r *GRPCRegistry) Get(ctx context.Context, name string) (*server.Service, error) {
+	service, err := r.client.Get(r.context(ctx), &registrypb.GetRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return server.FromProtoService(service), nil
+}
+
+func (r *GRPCRegistry) Register(ctx context.Context, req RegisterRequest) (RegisterResponse, error) {
+	response, err := r.client.Register(r.context(ctx), &registrypb.RegisterRequest{
+		Name:     req.Name,
+		Callback: req.Callback,
+		Port:     int32(req.Port),
+		Address:  req.Address,
+		Tags:     req.Tags,
+		Metadata: req.Metadata,
+	})
+	if err != nil {
+		return RegisterResponse{}, err
+	}
+	return RegisterResponse{Hash: response.GetHash()}, nil
+}
+
+func (r *GRPCRegistry) Deregister(ctx context.Context, name string) error {
+	_, err := r.client.Deregister(r.context(ctx), &registrypb.DeregisterRequest{Name: name})
+	return err
+}
+
+// DeregisterInstance removes the registration with hash only, leaving a newer
+// registration of name alone. It returns ErrNotRegistered when the registry
+// no longer holds it.
+func (r *GRPCRegistry) DeregisterInstance(ctx context.Context, name, hash string) error {
+	_, err := r.client.Deregister(r.context(ctx), &registrypb.DeregisterRequest{Name: name, Hash: hash})
+	if status.Code(err) == codes.NotFound {
+		return ErrNotRegistered
+	}
+	return err
+}
+
+func (r *GRPCRegistry) Renew(ctx context.Context, name, hash string) error {
+	_, err := r.client.Renew(r.context(ctx), &registrypb.RenewRequest{Name: name, Hash: hash})
+	if status.Code(err) == codes.NotFound {
+		return ErrNotRegistered
+	}
+	return err
+}
+
+// Watch streams the service list on every registry change. Broken streams are
+// reported to onError and resubscribed after a second; the channel is closed
+// when ctx is done.
+func (r *GRPCRegistry) Watch(ctx context.Context, onError func(error)) <-chan []*server.Service {
+	ch := make(chan []*server.Service)
+
+	go func() {
+		defer close(ch)
+
+		for {
+			err := r.watch(ctx, ch)
+			if ctx.Err() != nil {
+				return
+			}
+			if onError != nil {
+				onError(err)
+			}
+
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+func (r *GRPCRegistry) watch(ctx context.Context, ch chan<- []*server.Service) error {
+	stream, err := r.client.Watch(r.context(ctx), &registrypb.WatchRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case ch <- fromProtoServices(response.GetServices()):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *GRPCRegistry) context(ctx context.Context) context.Context {
+	if r.token == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+r.token)
+}
+
+func fromProtoServices(services []*registrypb.Service) []*server.Service {
+	out := make([]*server.Service, 0, len(services))
+	for _, service := range services {
+		out = append(out, server.FromProtoService(service))
+	}
+	return out
+}
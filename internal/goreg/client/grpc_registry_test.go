@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func setupGRPCRegistry(t *testing.T) (*server.Server, *grpc.ClientConn) {
+	t.Helper()
+
+	httpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	grpcLn := bufconn.Listen(1 << 20)
+
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079, GRPC: server.GRPCConfig{Enabled: true}},
+		server.WithLogger(zap.NewNop()),
+		server.WithListener(httpLn),
+		server.WithGRPCListener(grpcLn),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, srv.Start())
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return grpcLn.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	})
+
+	return srv, conn
+}
+
+func TestClient_GRPCTransport(t *testing.T) {
+	_, conn := setupGRPCRegistry(t)
+
+	events := &recordingEvents{}
+	client, err := NewClient(ClientConfig{
+		Registrator: "http://registrator.url",
+		Callback:    "http://callback.url",
+		Name:        "test-client",
+		Port:        8080,
+		Tags:        []string{"grpc"},
+	}, WithLogger(zap.NewNop()), WithGRPCConn(conn), WithEventHandler(events))
+	assert.NoError(t, err)
+
+	client.doRegister()
+	hash := client.store.hash()
+	assert.NotEmpty(t, hash)
+
+	registry := NewGRPCRegistry(conn, "")
+	service, err := registry.Get(context.Background(), "test-client")
+	assert.NoError(t, err)
+	assert.Equal(t, hash, service.Hash)
+	assert.Equal(t, []string{"grpc"}, service.Tags)
+
+	client.doHeartbeat()
+	assert.Empty(t, events.deregistered)
+
+	assert.NoError(t, registry.Deregister(context.Background(), "test-client"))
+	client.doHeartbeat()
+	assert.Equal(t, []string{"test-client"}, events.deregistered)
+	assert.NotEqual(t, hash, client.store.hash())
+}
+
+func TestGRPCRegistry_DeregisterInstance(t *testing.T) {
+	_, conn := setupGRPCRegistry(t)
+	registry := NewGRPCRegistry(conn, "")
+	ctx := context.Background()
+
+	old, err := registry.Register(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"})
+	assert.NoError(t, err)
+	assert.NoError(t, registry.Deregister(ctx, "orders"))
+	newer, err := registry.Register(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, registry.DeregisterInstance(ctx, "orders", old.Hash), ErrNotRegistered)
+
+	service, err := registry.Get(ctx, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, newer.Hash, service.Hash)
+
+	assert.NoError(t, registry.DeregisterInstance(ctx, "orders", newer.Hash))
+	assert.ErrorIs(t, registry.DeregisterInstance(ctx, "orders", newer.Hash), ErrNotRegistered)
+}
+
+func TestGRPCRegistry_Watch(t *testing.T) {
+	_, conn := setupGRPCRegistry(t)
+	registry := NewGRPCRegistry(conn, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updates := registry.Watch(ctx, nil)
+	assert.Empty(t, <-updates)
+
+	_, err := registry.Register(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"})
+	assert.NoError(t, err)
+
+	services := <-updates
+	assert.Len(t, services, 1)
+	assert.Equal(t, "orders", services[0].Name)
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "goreg_client"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	registrationAttempts prometheus.Counter
+	registrationRetries  prometheus.Counter
+	heartbeatDuration    *prometheus.HistogramVec
+	probes               *prometheus.CounterVec
+	cacheHits            prometheus.Counter
+	cacheMisses          prometheus.Counter
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		registrationAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "registration_attempts_total",
+			Help:      "Number of registration requests sent to the registry.",
+		}),
+		registrationRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "registration_retries_total",
+			Help:      "Number of registration requests that retried a failed attempt.",
+		}),
+		heartbeatDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "heartbeat_duration_seconds",
+			Help:      "Latency of heartbeats to the registry by result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		probes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "callback_probes_total",
+			Help:      "Number of callback probes received from the registry by result.",
+		}, []string{"result"}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "discovery_cache_hits_total",
+			Help:      "Number of discovery lookups served from the cache.",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "discovery_cache_misses_total",
+			Help:      "Number of discovery lookups that queried the registry.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.registrationAttempts,
+		m.registrationRetries,
+		m.heartbeatDuration,
+		m.probes,
+		m.cacheHits,
+		m.cacheMisses,
+	)
+
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry is meant to be combined with the application registry, e.g. via
+// prometheus.Gatherers.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+func (m *Metrics) attempted(retry bool) {
+	if m == nil {
+		return
+	}
+
+	m.registrationAttempts.Inc()
+	if retry {
+		m.registrationRetries.Inc()
+	}
+}
+
+func (m *Metrics) heartbeat(result string, seconds float64) {
+	if m != nil {
+		m.heartbeatDuration.WithLabelValues(result).Observe(seconds)
+	}
+}
+
+func (m *Metrics) probed(ok bool) {
+	if m == nil {
+		return
+	}
+
+	result := "ok"
+	if !ok {
+		result = "mismatch"
+	}
+	m.probes.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) cacheLookup(hit bool) {
+	if m == nil {
+		return
+	}
+
+	if hit {
+		m.cacheHits.Inc()
+		return
+	}
+	m.cacheMisses.Inc()
+}
@@ -0,0 +1,319 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/httpprovider"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/Danis0n/goreg/internal/goreg/validation"
+)
+
+// Registry talks to the registry API directly. Unlike Client it does not
+// register itself; it is meant for tooling and service discovery.
+type Registry struct {
+	address    string
+	token      string
+	httpClient HTTPClient
+}
+
+const blockingMargin = 5 * time.Second
+
+func NewRegistry(address, token string, opts ...Option) (*Registry, error) {
+	if err := validation.URL("address", address); err != nil {
+		return nil, err
+	}
+
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := o.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Registry{
+		address:    strings.TrimSuffix(address, "/"),
+		token:      token,
+		httpClient: httpClient,
+	}, nil
+}
+
+func (r *Registry) List(ctx context.Context) ([]*server.Service, error) {
+	var services []*server.Service
+	if err := r.do(ctx, http.MethodGet, "/v1/services", nil, nil, &services); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	return services, nil
+}
+
+// WaitList is a blocking List: it returns once the registry index is past
+// index, or after wait, together with the index to pass to the next call. An
+// index of 0 returns at once. Registries that do not report an index return 0.
+// wait is shortened to fit the timeout of the HTTP client.
+func (r *Registry) WaitList(ctx context.Context, index uint64, wait time.Duration) ([]*server.Service, uint64, error) {
+	query := url.Values{
+		"index": {strconv.FormatUint(index, 10)},
+		"wait":  {r.maxWait(wait).String()},
+	}
+
+	var services []*server.Service
+	header, err := r.send(ctx, http.MethodGet, "/v1/services", query, nil, &services)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	next, _ := strconv.ParseUint(header.Get(server.IndexHeader), 10, 64)
+	return services, next, nil
+}
+
+func (r *Registry) Get(ctx context.Context, name string) (*server.Service, error) {
+	var service server.Service
+	if err := r.do(ctx, http.MethodGet, servicePath(name), nil, nil, &service); err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
+
+func (r *Registry) Register(ctx context.Context, req RegisterRequest) (RegisterResponse, error) {
+	var response RegisterResponse
+	if err := r.do(ctx, http.MethodPost, "/v1/services", nil, req, &response); err != nil {
+		return RegisterResponse{}, err
+	}
+	return response, nil
+}
+
+// Update replaces the callback, address, port, tags and metadata of the
+// registration of req.Name. A non-zero index makes the write conditional on
+// the ModifyIndex of the service; a stale index fails with 409 Conflict.
+func (r *Registry) Update(ctx context.Context, req RegisterRequest, index uint64) (*server.Service, error) {
+	var query url.Values
+	if index != 0 {
+		query = url.Values{"cas": {strconv.FormatUint(index, 10)}}
+	}
+
+	var service server.Service
+	if err := r.do(ctx, http.MethodPut, servicePath(req.Name), query, req, &service); err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
+
+func (r *Registry) Deregister(ctx context.Context, name string) error {
+	return r.do(ctx, http.MethodDelete, servicePath(name), nil, nil, nil)
+}
+
+// DeregisterInstance removes the registration with hash only, leaving a newer
+// registration of name alone. It returns ErrNotRegistered when the registry
+// no longer holds it.
+func (r *Registry) DeregisterInstance(ctx context.Context, name, hash string) error {
+	err := r.do(ctx, http.MethodDelete, servicePath(name)+"/instances/"+url.PathEscape(hash), nil, nil, nil)
+
+	var statusErr *httpprovider.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return ErrNotRegistered
+	}
+	return err
+}
+
+// Renew checks that the registry still holds the registration with hash. It
+// is sent as a batch renew, which like the gRPC Renew needs register on name.
+func (r *Registry) Renew(ctx context.Context, name, hash string) error {
+	op := server.BatchOp{Verb: server.BatchRenew, Service: server.Service{Name: name, Hash: hash}}
+	err := r.do(ctx, http.MethodPost, "/v1/batch", nil, server.BatchRequest{Operations: []server.BatchOp{op}}, nil)
+
+	var statusErr *httpprovider.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return ErrNotRegistered
+	}
+	return err
+}
+
+// ErrBatchRejected is returned by Batch when the registry applied none of the
+// operations. The response tells which of them failed.
+var ErrBatchRejected = errors.New("goreg->[client]: batch rejected")
+
+// Batch applies ops in one registry transaction: either all of them or none.
+func (r *Registry) Batch(ctx context.Context, ops []server.BatchOp) (server.BatchResponse, error) {
+	var response server.BatchResponse
+	err := r.do(ctx, http.MethodPost, "/v1/batch", nil, server.BatchRequest{Operations: ops}, &response)
+
+	var statusErr *httpprovider.StatusError
+	if errors.As(err, &statusErr) && json.Unmarshal([]byte(statusErr.Body), &response) == nil && len(response.Results) > 0 {
+		for _, result := range response.Results {
+			if result.Code != server.CodeBatchAborted {
+				return response, fmt.Errorf("%w: %s: %s", ErrBatchRejected, result.Name, result.Detail)
+			}
+		}
+		return response, ErrBatchRejected
+	}
+	if err != nil {
+		return server.BatchResponse{}, err
+	}
+	return response, nil
+}
+
+// RegisterAll registers every request in one batch, so that either all of
+// them or none are registered. Responses are in request order.
+func (r *Registry) RegisterAll(ctx context.Context, reqs []RegisterRequest) ([]RegisterResponse, error) {
+	ops := make([]server.BatchOp, len(reqs))
+	for i, req := range reqs {
+		ops[i] = server.BatchOp{Verb: server.BatchRegister, Service: server.Service{
+			Name:     req.Name,
+			Callback: req.Callback,
+			Address:  req.Address,
+			Port:     req.Port,
+			Tags:     req.Tags,
+			Metadata: req.Metadata,
+		}}
+	}
+
+	response, err := r.Batch(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]RegisterResponse, len(response.Results))
+	for i, result := range response.Results {
+		responses[i] = RegisterResponse{Hash: result.Service.Hash}
+	}
+	return responses, nil
+}
+
+func (r *Registry) Health(ctx context.Context) (server.HealthResponse, error) {
+	var health server.HealthResponse
+	if err := r.do(ctx, http.MethodGet, "/health", nil, nil, &health); err != nil {
+		return server.HealthResponse{}, err
+	}
+	return health, nil
+}
+
+// Watch sends the full service list each time it differs from the previous
+// one. The first list is always sent. Changes are picked up with blocking
+// queries that wait at most interval; registries that do not support them are
+// polled every interval instead. The channel is closed when ctx is done;
+// failed queries are passed to onError.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration, onError func(error)) <-chan []*server.Service {
+	ch := make(chan []*server.Service)
+
+	go func() {
+		defer close(ch)
+
+		var (
+			last  string
+			index uint64
+		)
+		for {
+			services, next, err := r.WaitList(ctx, index, interval)
+			if err != nil && ctx.Err() == nil && onError != nil {
+				onError(err)
+			}
+
+			if err == nil {
+				snapshot, _ := json.Marshal(services)
+				if string(snapshot) != last {
+					last = string(snapshot)
+					select {
+					case ch <- services:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			if err == nil && next != 0 {
+				index = next
+				continue
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// maxWait keeps a blocking query shorter than the client timeout, leaving
+// blockingMargin, or half of a shorter timeout, for the response to arrive.
+func (r *Registry) maxWait(wait time.Duration) time.Duration {
+	client, ok := r.httpClient.(*http.Client)
+	if !ok || client.Timeout <= 0 {
+		return wait
+	}
+	return min(wait, max(client.Timeout-blockingMargin, client.Timeout/2))
+}
+
+func servicePath(name string) string {
+	return "/v1/services/" + url.PathEscape(name)
+}
+
+func (r *Registry) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	_, err := r.send(ctx, method, path, query, in, out)
+	return err
+}
+
+// send is do that also returns the response headers.
+func (r *Registry) send(ctx context.Context, method, path string, query url.Values, in, out any) (http.Header, error) {
+	target := r.address + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(in); err != nil {
+			return nil, err
+		}
+		body = buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	data, header, err := httpprovider.Do(req, r.httpClient)
+	if err != nil {
+		return header, err
+	}
+
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return header, nil
+	}
+
+	return header, json.Unmarshal(data, out)
+}
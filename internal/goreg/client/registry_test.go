@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/httpprovider"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func setupTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079}, server.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+
+	registry, err := NewRegistry(ts.URL, "")
+	assert.NoError(t, err)
+	return registry
+}
+
+func TestNewRegistry_InvalidAddress(t *testing.T) {
+	_, err := NewRegistry("abc", "")
+	assert.Error(t, err)
+}
+
+func TestRegistry_RegisterGetListDeregister(t *testing.T) {
+	registry := setupTestRegistry(t)
+	ctx := context.Background()
+
+	response, err := registry.Register(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Hash)
+
+	service, err := registry.Get(ctx, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, response.Hash, service.Hash)
+
+	_, err = registry.Register(ctx, RegisterRequest{Name: "billing", Callback: "http://billing:8080/callback"})
+	assert.NoError(t, err)
+
+	services, err := registry.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+	assert.Equal(t, "billing", services[0].Name)
+
+	assert.NoError(t, registry.Deregister(ctx, "orders"))
+
+	_, err = registry.Get(ctx, "orders")
+	var statusErr *httpprovider.StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.Code)
+	assert.Equal(t, server.CodeServiceNotFound, statusErr.Problem)
+	assert.Equal(t, "service orders is not registered", statusErr.Body)
+}
+
+func TestRegistry_Update(t *testing.T) {
+	registry := setupTestRegistry(t)
+	ctx := context.Background()
+
+	response, err := registry.Register(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"})
+	assert.NoError(t, err)
+
+	service, err := registry.Get(ctx, "orders")
+	assert.NoError(t, err)
+
+	updated, err := registry.Update(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:9090/callback", Tags: []string{"v2"}}, service.ModifyIndex)
+	assert.NoError(t, err)
+	assert.Equal(t, response.Hash, updated.Hash)
+	assert.Equal(t, []string{"v2"}, updated.Tags)
+	assert.Greater(t, updated.ModifyIndex, service.ModifyIndex)
+
+	_, err = registry.Update(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"}, service.ModifyIndex)
+	var statusErr *httpprovider.StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusConflict, statusErr.Code)
+	assert.Equal(t, server.CodeIndexConflict, statusErr.Problem)
+}
+
+func TestRegistry_RegisterAll(t *testing.T) {
+	registry := setupTestRegistry(t)
+	ctx := context.Background()
+
+	responses, err := registry.RegisterAll(ctx, []RegisterRequest{
+		{Name: "orders", Callback: "http://orders:8080/callback"},
+		{Name: "orders-metrics", Callback: "http://orders:9090/callback", Tags: []string{"metrics"}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, responses, 2)
+
+	metrics, err := registry.Get(ctx, "orders-metrics")
+	assert.NoError(t, err)
+	assert.Equal(t, responses[1].Hash, metrics.Hash)
+	assert.Equal(t, []string{"metrics"}, metrics.Tags)
+
+	_, err = registry.RegisterAll(ctx, []RegisterRequest{
+		{Name: "billing", Callback: "http://billing:8080/callback"},
+		{Name: "orders", Callback: "http://orders:8080/callback"},
+	})
+	assert.ErrorIs(t, err, ErrBatchRejected)
+
+	_, err = registry.Get(ctx, "billing")
+	assert.Error(t, err, "rejected batch registered billing")
+
+	response, err := registry.Batch(ctx, []server.BatchOp{
+		{Verb: server.BatchRenew, Service: server.Service{Name: "orders", Hash: responses[0].Hash}},
+		{Verb: server.BatchDeregister, Service: server.Service{Name: "orders-metrics"}},
+	})
+	assert.NoError(t, err)
+	assert.True(t, response.Applied)
+	assert.Equal(t, responses[0].Hash, response.Results[0].Service.Hash)
+}
+
+func TestRegistry_Health(t *testing.T) {
+	registry := setupTestRegistry(t)
+
+	health, err := registry.Health(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", health.Status)
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	registry := setupTestRegistry(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := registry.Watch(ctx, 10*time.Millisecond, nil)
+
+	first := <-updates
+	assert.Empty(t, first)
+
+	_, err := registry.Register(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"})
+	assert.NoError(t, err)
+
+	second := <-updates
+	assert.Len(t, second, 1)
+	assert.Equal(t, "orders", second[0].Name)
+
+	cancel()
+	for range updates {
+	}
+}
+
+func TestRegistry_WaitList(t *testing.T) {
+	registry := setupTestRegistry(t)
+	ctx := context.Background()
+
+	_, err := registry.Register(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"})
+	assert.NoError(t, err)
+
+	services, index, err := registry.WaitList(ctx, 0, time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, uint64(1), index)
+
+	type result struct {
+		services []*server.Service
+		index    uint64
+	}
+	done := make(chan result, 1)
+	go func() {
+		services, index, err := registry.WaitList(ctx, index, 5*time.Second)
+		assert.NoError(t, err)
+		done <- result{services, index}
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("blocking list returned before a change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_, err = registry.Register(ctx, RegisterRequest{Name: "billing", Callback: "http://billing:8080/callback"})
+	assert.NoError(t, err)
+
+	select {
+	case got := <-done:
+		assert.Len(t, got.services, 2)
+		assert.Equal(t, uint64(2), got.index)
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocking list did not return after a change")
+	}
+
+	_, index, err = registry.WaitList(ctx, 2, 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), index)
+}
+
+func TestRegistry_WaitListClientTimeout(t *testing.T) {
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079}, server.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	registry, err := NewRegistry(ts.URL, "", WithHTTPClient(&http.Client{Timeout: 200 * time.Millisecond}))
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = registry.Register(ctx, RegisterRequest{Name: "orders", Callback: "http://orders:8080/callback"})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	services, index, err := registry.WaitList(ctx, 1, time.Minute)
+	assert.NoError(t, err, "a wait longer than the client timeout is shortened")
+	assert.Len(t, services, 1)
+	assert.Equal(t, uint64(1), index)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestRegistry_MaxWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		wait    time.Duration
+		want    time.Duration
+	}{
+		{"No timeout", 0, time.Hour, time.Hour},
+		{"Shorter than timeout", 30 * time.Second, 10 * time.Second, 10 * time.Second},
+		{"Leaves a margin", 30 * time.Second, time.Minute, 25 * time.Second},
+		{"Short timeout", 4 * time.Second, time.Minute, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := NewRegistry("http://registry:8079", "", WithHTTPClient(&http.Client{Timeout: tt.timeout}))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, registry.maxWait(tt.wait))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestClientTracing_RegisterPropagatesToRegistry(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079},
+		server.WithLogger(zap.NewNop()),
+		server.WithTracerProvider(tp),
+	)
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	client, err := NewClient(ClientConfig{
+		Registrator: ts.URL,
+		Callback:    "http://callback.url",
+		Name:        "test-client",
+		Port:        8080,
+	}, WithLogger(zap.NewNop()), WithTracerProvider(tp))
+	assert.NoError(t, err)
+
+	client.doRegister()
+	assert.NotEmpty(t, client.store.hash())
+
+	spans := exporter.GetSpans()
+	names := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		names[span.Name] = span
+	}
+
+	register, request, handler := names["goreg.register"], names["HTTP POST"], names["POST /v1/services"]
+	assert.Len(t, spans, 3)
+	assert.Equal(t, register.SpanContext.SpanID(), request.Parent.SpanID())
+	assert.Equal(t, request.SpanContext.SpanID(), handler.Parent.SpanID())
+	assert.True(t, handler.Parent.IsRemote())
+	assert.Equal(t, register.SpanContext.TraceID(), handler.SpanContext.TraceID())
+}
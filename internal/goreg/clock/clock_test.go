@@ -0,0 +1,47 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManual_After(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewManual(start)
+
+	ch := c.After(time.Minute)
+	if c.Waiters() != 1 {
+		t.Fatalf("expected 1 waiter, got %d", c.Waiters())
+	}
+
+	c.Advance(30 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("timer fired before its deadline")
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	select {
+	case now := <-ch:
+		if !now.Equal(start.Add(time.Minute)) {
+			t.Errorf("expected %v, got %v", start.Add(time.Minute), now)
+		}
+	default:
+		t.Fatal("timer did not fire after its deadline")
+	}
+
+	if c.Waiters() != 0 {
+		t.Errorf("expected no waiters, got %d", c.Waiters())
+	}
+}
+
+func TestManual_AfterZero(t *testing.T) {
+	c := NewManual(time.Now())
+
+	select {
+	case <-c.After(0):
+	default:
+		t.Fatal("expected zero duration timer to fire immediately")
+	}
+}
@@ -0,0 +1,184 @@
+package goreg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/client"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvPrefix = "GOREG"
+
+	DefaultServerPort = 8079
+)
+
+// LoadServerConfig reads a YAML or JSON server config from path (an empty path
+// skips the file), applies GOREG_* environment overrides and defaults and
+// validates the result. Every invalid field is reported in the returned error.
+func LoadServerConfig(path string) (server.ServerConfig, error) {
+	var cfg server.ServerConfig
+
+	envErr, err := loadConfig(path, &cfg)
+	if err != nil {
+		return server.ServerConfig{}, err
+	}
+
+	if cfg.Port == 0 {
+		cfg.Port = DefaultServerPort
+	}
+
+	if cfg.ACL.Enabled && cfg.ACL.DefaultPolicy == "" {
+		cfg.ACL.DefaultPolicy = server.ACLPolicyDeny
+	}
+
+	if err := errors.Join(envErr, server.ValidateServerConfig(cfg)); err != nil {
+		return server.ServerConfig{}, configError(path, err)
+	}
+
+	return cfg, nil
+}
+
+// LoadClientConfig is the client counterpart of LoadServerConfig.
+func LoadClientConfig(path string) (client.ClientConfig, error) {
+	var cfg client.ClientConfig
+
+	envErr, err := loadConfig(path, &cfg)
+	if err != nil {
+		return client.ClientConfig{}, err
+	}
+
+	if cfg.Name == "" {
+		cfg.Name = uuid.New().String()
+	}
+
+	if err := errors.Join(envErr, client.ValidateClientConfig(cfg)); err != nil {
+		return client.ClientConfig{}, configError(path, err)
+	}
+
+	return cfg, nil
+}
+
+// loadConfig decodes the file into cfg and applies environment overrides. Bad
+// override values are returned separately so they are reported together with
+// the validation errors.
+func loadConfig(path string, cfg any) (envErr error, err error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.New("goreg: read config: " + err.Error())
+		}
+
+		if err := decodeConfig(data, cfg); err != nil {
+			return nil, configError(path, err)
+		}
+	}
+
+	return applyEnvOverrides(EnvPrefix, reflect.ValueOf(cfg).Elem()), nil
+}
+
+// decodeConfig reads JSON files with the YAML decoder too: JSON is valid YAML,
+// so both formats go by the yaml tags and take durations such as "30s".
+func decodeConfig(data []byte, cfg any) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnvOverrides walks the yaml tags of cfg and overrides every scalar
+// field from PREFIX_<TAG>, nesting structs with "_": tls.cert_file becomes
+// GOREG_TLS_CERT_FILE. Lists are left to the config file.
+func applyEnvOverrides(prefix string, cfg reflect.Value) error {
+	var errs []error
+
+	for i := 0; i < cfg.NumField(); i++ {
+		field := cfg.Type().Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name := prefix + "_" + strings.ToUpper(tag)
+		value := cfg.Field(i)
+
+		if value.Kind() == reflect.Struct {
+			errs = append(errs, applyEnvOverrides(name, value))
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err := setScalar(value, raw); err != nil {
+			errs = append(errs, errors.New(name+" invalid: "+err.Error()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func setScalar(value reflect.Value, raw string) error {
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	default:
+		return errors.New("unsupported type " + value.Type().String())
+	}
+
+	return nil
+}
+
+func configError(path string, err error) error {
+	if path == "" {
+		path = "environment"
+	}
+	return fmt.Errorf("goreg: invalid config %s:\n%w", path, err)
+}
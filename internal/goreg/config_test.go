@@ -0,0 +1,208 @@
+package goreg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/server"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadServerConfig_YAML(t *testing.T) {
+	path := writeConfig(t, "server.yaml", `
+port: 9000
+acl:
+  enabled: true
+  policies:
+    - name: admin
+      rules:
+        - service: "*"
+          capabilities: [admin]
+  tokens:
+    - id: root
+      secret: root-secret
+      policies: [admin]
+`)
+
+	cfg, err := LoadServerConfig(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Port != 9000 {
+		t.Errorf("expected port 9000, got %d", cfg.Port)
+	}
+
+	if cfg.ACL.DefaultPolicy != server.ACLPolicyDeny {
+		t.Errorf("expected default acl policy %s, got %s", server.ACLPolicyDeny, cfg.ACL.DefaultPolicy)
+	}
+
+	if len(cfg.ACL.Tokens) != 1 || cfg.ACL.Tokens[0].Secret != "root-secret" {
+		t.Errorf("expected acl tokens to be loaded, got %v", cfg.ACL.Tokens)
+	}
+}
+
+func TestLoadServerConfig_JSONTokens(t *testing.T) {
+	path := writeConfig(t, "server.json", `{
+		"acl": {
+			"enabled": true,
+			"policies": [{"name": "admin", "rules": [{"service": "*", "capabilities": ["admin"]}]}],
+			"tokens": [{"id": "root", "secret": "root-secret", "policies": ["admin"]}]
+		}
+	}`)
+
+	cfg, err := LoadServerConfig(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(cfg.ACL.Tokens) != 1 || cfg.ACL.Tokens[0].Secret != "root-secret" {
+		t.Errorf("expected acl token secret to be loaded, got %v", cfg.ACL.Tokens)
+	}
+}
+
+func TestLoadServerConfig_JSONDurations(t *testing.T) {
+	path := writeConfig(t, "server.json", `{
+		"check_interval": "30s",
+		"evict_after": "10m",
+		"history": {"flap_window": "2m"}
+	}`)
+
+	cfg, err := LoadServerConfig(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.CheckInterval != 30*time.Second || cfg.EvictAfter != 10*time.Minute || cfg.History.FlapWindow != 2*time.Minute {
+		t.Errorf("unexpected durations: %v %v %v", cfg.CheckInterval, cfg.EvictAfter, cfg.History.FlapWindow)
+	}
+}
+
+func TestLoadServerConfig_JSONUnknownField(t *testing.T) {
+	path := writeConfig(t, "server.json", `{"prot": 9000}`)
+
+	if _, err := LoadServerConfig(path); err == nil {
+		t.Fatal("expected error for unknown field, got nil")
+	}
+}
+
+func TestLoadServerConfig_Defaults(t *testing.T) {
+	cfg, err := LoadServerConfig("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Port != DefaultServerPort {
+		t.Errorf("expected default port %d, got %d", DefaultServerPort, cfg.Port)
+	}
+}
+
+func TestLoadServerConfig_EnvOverrides(t *testing.T) {
+	path := writeConfig(t, "server.json", `{"port": 9000}`)
+	t.Setenv("GOREG_PORT", "9100")
+	t.Setenv("GOREG_TLS_CA_FILE", "/etc/goreg/ca.pem")
+
+	cfg, err := LoadServerConfig(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Port != 9100 {
+		t.Errorf("expected port from env 9100, got %d", cfg.Port)
+	}
+
+	if cfg.TLS.CAFile != "/etc/goreg/ca.pem" {
+		t.Errorf("expected ca file from env, got %s", cfg.TLS.CAFile)
+	}
+}
+
+func TestLoadServerConfig_UnknownField(t *testing.T) {
+	path := writeConfig(t, "server.yaml", "prot: 9000\n")
+
+	if _, err := LoadServerConfig(path); err == nil {
+		t.Fatal("expected error for unknown field, got nil")
+	}
+}
+
+func TestLoadClientConfig_JSON(t *testing.T) {
+	path := writeConfig(t, "client.json", `{
+		"address": "http://registry:8079",
+		"callback_address": "http://orders:8080",
+		"name": "orders",
+		"port": 8080,
+		"heartbeat_interval": "15s"
+	}`)
+	t.Setenv("GOREG_TOKEN", "orders-secret")
+	t.Setenv("GOREG_ADVERTISE_ADDRESS", "10.0.0.7")
+
+	cfg, err := LoadClientConfig(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Name != "orders" || cfg.Port != 8080 || cfg.Registrator != "http://registry:8079" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	if cfg.HeartbeatInterval != 15*time.Second {
+		t.Errorf("expected heartbeat interval 15s, got %v", cfg.HeartbeatInterval)
+	}
+
+	if cfg.Token != "orders-secret" {
+		t.Errorf("expected token from env, got %s", cfg.Token)
+	}
+
+	if cfg.Advertise.Address != "10.0.0.7" {
+		t.Errorf("expected advertise address from env, got %s", cfg.Advertise.Address)
+	}
+}
+
+func TestLoadClientConfig_LegacyRegistrator(t *testing.T) {
+	path := writeConfig(t, "client.json", `{
+		"address": "http://registry:8079/set",
+		"callback_address": "http://orders:8080",
+		"name": "orders",
+		"port": 8080
+	}`)
+
+	cfg, err := LoadClientConfig(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Name != "orders" || cfg.Port != 8080 || cfg.Registrator != "http://registry:8079/set" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	if address := cfg.RegistryAddress(); address != "http://registry:8079" {
+		t.Errorf("expected registry address without /set, got %s", address)
+	}
+}
+
+func TestLoadClientConfig_AggregatedErrors(t *testing.T) {
+	path := writeConfig(t, "client.yaml", "name: orders\ncallback_address: not-a-url\n")
+	t.Setenv("GOREG_PORT", "not-a-number")
+
+	_, err := LoadClientConfig(path)
+	if err == nil {
+		t.Fatal("expected error for invalid config, got nil")
+	}
+
+	for _, field := range []string{"GOREG_PORT", "callback_address", "address", "port"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected error to name %s, got %v", field, err)
+		}
+	}
+}
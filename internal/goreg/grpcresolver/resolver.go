@@ -0,0 +1,147 @@
+// Package grpcresolver resolves goreg:///<service> gRPC targets from the
+// registry and keeps connections in sync with registry changes.
+package grpcresolver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+const Scheme = "goreg"
+
+// Watcher streams the registry content. client.Client and client.GRPCRegistry
+// implement it; wrap client.Registry.Watch in a WatcherFunc.
+type Watcher interface {
+	Watch(ctx context.Context, onError func(error)) <-chan []*server.Service
+}
+
+type WatcherFunc func(ctx context.Context, onError func(error)) <-chan []*server.Service
+
+func (f WatcherFunc) Watch(ctx context.Context, onError func(error)) <-chan []*server.Service {
+	return f(ctx, onError)
+}
+
+type instanceKey struct{}
+
+// Instance is attached to every resolved address as a balancer attribute, so
+// balancer policies can pick instances by tags and metadata.
+type Instance struct {
+	Name     string
+	Hash     string
+	Tags     []string
+	Metadata map[string]string
+}
+
+// Equal lets gRPC compare attributes holding an Instance.
+func (i Instance) Equal(o any) bool {
+	other, ok := o.(Instance)
+	return ok && reflect.DeepEqual(i, other)
+}
+
+// InstanceFromAddress returns the registry instance behind addr.
+func InstanceFromAddress(addr resolver.Address) (Instance, bool) {
+	instance, ok := addr.BalancerAttributes.Value(instanceKey{}).(Instance)
+	return instance, ok
+}
+
+type builder struct {
+	watcher Watcher
+}
+
+// NewBuilder returns a resolver builder for the goreg scheme. Pass it to
+// grpc.WithResolvers, or to resolver.Register to make it global.
+func NewBuilder(w Watcher) resolver.Builder {
+	return &builder{watcher: w}
+}
+
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	name := strings.TrimPrefix(target.Endpoint(), "/")
+	if name == "" {
+		return nil, errors.New("goreg->[resolver]: target has no service name: " + target.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &goregResolver{
+		name:   name,
+		cc:     cc,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	updates := b.watcher.Watch(ctx, cc.ReportError)
+	go r.run(updates)
+
+	return r, nil
+}
+
+type goregResolver struct {
+	name   string
+	cc     resolver.ClientConn
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (r *goregResolver) run(updates <-chan []*server.Service) {
+	defer close(r.done)
+
+	for services := range updates {
+		addresses := r.addresses(services)
+
+		// An empty state drops the instances that went away, the error
+		// tells callers why nothing is left to dial.
+		r.cc.UpdateState(resolver.State{Addresses: addresses})
+		if len(addresses) == 0 {
+			r.cc.ReportError(errors.New("goreg->[resolver]: no passing instances of " + r.name))
+		}
+	}
+}
+
+// addresses keeps the passing instances of the resolved service, ordered by
+// address so identical content yields identical state.
+func (r *goregResolver) addresses(services []*server.Service) []resolver.Address {
+	addresses := make([]resolver.Address, 0, len(services))
+	for _, service := range services {
+		if service.Name != r.name || service.Status != server.StatusPassing {
+			continue
+		}
+
+		target, err := service.Target()
+		if err != nil {
+			continue
+		}
+
+		addresses = append(addresses, resolver.Address{
+			Addr: target,
+			BalancerAttributes: attributes.New(instanceKey{}, Instance{
+				Name:     service.Name,
+				Hash:     service.Hash,
+				Tags:     slices.Clone(service.Tags),
+				Metadata: service.Metadata,
+			}),
+		})
+	}
+
+	slices.SortFunc(addresses, func(a, b resolver.Address) int {
+		return strings.Compare(a.Addr, b.Addr)
+	})
+	return addresses
+}
+
+// ResolveNow is a no-op: the watch already delivers every change.
+func (r *goregResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *goregResolver) Close() {
+	r.cancel()
+	<-r.done
+}
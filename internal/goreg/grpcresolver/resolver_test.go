@@ -0,0 +1,204 @@
+package grpcresolver
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/client"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+)
+
+func setupRegistry(t *testing.T) *client.Registry {
+	t.Helper()
+
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079}, server.WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+
+	registry, err := client.NewRegistry(ts.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func pollingWatcher(registry *client.Registry) Watcher {
+	return WatcherFunc(func(ctx context.Context, onError func(error)) <-chan []*server.Service {
+		return registry.Watch(ctx, 10*time.Millisecond, onError)
+	})
+}
+
+func TestResolver_Dial(t *testing.T) {
+	registry := setupRegistry(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := grpc.NewServer()
+	healthpb.RegisterHealthServer(backend, health.NewServer())
+	go backend.Serve(ln)
+	defer backend.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = registry.Register(ctx, client.RegisterRequest{
+		Name:     "echo",
+		Callback: "http://127.0.0.1:1/callback",
+		Address:  "127.0.0.1",
+		Port:     ln.Addr().(*net.TCPAddr).Port,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := grpc.NewClient("goreg:///echo",
+		grpc.WithResolvers(NewBuilder(pollingWatcher(registry))),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("unexpected health status %v", response.GetStatus())
+	}
+}
+
+type recordingConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+	errs   chan error
+}
+
+func (c *recordingConn) UpdateState(state resolver.State) error {
+	c.states <- state
+	return nil
+}
+
+func (c *recordingConn) ReportError(err error) {
+	if c.errs != nil {
+		c.errs <- err
+	}
+}
+
+func TestResolver_FollowsRegistry(t *testing.T) {
+	registry := setupRegistry(t)
+	ctx := context.Background()
+
+	_, err := registry.Register(ctx, client.RegisterRequest{
+		Name:     "orders",
+		Callback: "http://10.0.0.5:8080/callback",
+		Tags:     []string{"canary"},
+		Metadata: map[string]string{"zone": "eu-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = registry.Register(ctx, client.RegisterRequest{Name: "billing", Callback: "http://10.0.0.6:8080/callback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cc := &recordingConn{states: make(chan resolver.State, 16)}
+	r, err := NewBuilder(pollingWatcher(registry)).Build(resolver.Target{URL: mustParse(t, "goreg:///orders")}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	state := <-cc.states
+	if len(state.Addresses) != 1 || state.Addresses[0].Addr != "10.0.0.5:8080" {
+		t.Fatalf("unexpected addresses: %v", state.Addresses)
+	}
+
+	instance, ok := InstanceFromAddress(state.Addresses[0])
+	if !ok || instance.Name != "orders" || instance.Tags[0] != "canary" || instance.Metadata["zone"] != "eu-1" {
+		t.Errorf("unexpected instance attributes: %+v", instance)
+	}
+
+	if err := registry.Deregister(ctx, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Register(ctx, client.RegisterRequest{Name: "orders", Callback: "http://10.0.0.7:8080/callback"}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case state = <-cc.states:
+			if len(state.Addresses) == 1 && state.Addresses[0].Addr == "10.0.0.7:8080" {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("resolver did not follow the registry, last state %v", state.Addresses)
+		}
+	}
+}
+
+func TestResolver_NoPassingInstances(t *testing.T) {
+	updates := make(chan []*server.Service)
+	watcher := WatcherFunc(func(ctx context.Context, onError func(error)) <-chan []*server.Service {
+		return updates
+	})
+
+	cc := &recordingConn{states: make(chan resolver.State, 16), errs: make(chan error, 16)}
+	r, err := NewBuilder(watcher).Build(resolver.Target{URL: mustParse(t, "goreg:///orders")}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		close(updates)
+		r.Close()
+	}()
+
+	updates <- []*server.Service{{Name: "orders", Callback: "http://10.0.0.5:8080/callback", Status: server.StatusPassing}}
+	if state := <-cc.states; len(state.Addresses) != 1 {
+		t.Fatalf("unexpected addresses: %v", state.Addresses)
+	}
+
+	updates <- []*server.Service{{Name: "orders", Callback: "http://10.0.0.5:8080/callback", Status: server.StatusCritical}}
+	if state := <-cc.states; len(state.Addresses) != 0 {
+		t.Errorf("expected critical instance to be dropped, got %v", state.Addresses)
+	}
+
+	select {
+	case err := <-cc.errs:
+		if err == nil {
+			t.Error("expected an error to be reported")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an error for no passing instances")
+	}
+}
+
+func mustParse(t *testing.T, raw string) url.URL {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *u
+}
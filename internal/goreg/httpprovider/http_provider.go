@@ -1,30 +1,101 @@
 package httpprovider
 
 import (
-	"errors"
+	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Danis0n/goreg/internal/goreg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type HttpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// StatusError is returned by Request for non-2xx responses. For problem+json
+// bodies Body holds the problem detail and Problem its machine readable code.
+type StatusError struct {
+	Code    int
+	Status  string
+	Body    string
+	Problem string
+}
+
+func (e *StatusError) Error() string {
+	msg := "goreg: bad status code: " + e.Status
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Request sends req and returns the response body. When the request context
+// carries a span, the call is traced as its child and the trace context is
+// propagated to the callee.
 func Request(req *http.Request, client HttpClient) ([]byte, error) {
+	body, _, err := Do(req, client)
+	return body, err
+}
+
+// spanURL drops the userinfo and the query, which carries the registration
+// hash of health probes, from the URL recorded on spans.
+func spanURL(u *url.URL) string {
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+}
+
+// Do is Request that also returns the response headers.
+func Do(req *http.Request, client HttpClient) (body []byte, header http.Header, err error) {
+	ctx := req.Context()
+	tracer := tracing.Tracer(trace.SpanFromContext(ctx).TracerProvider())
+	ctx, span := tracer.Start(ctx, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(spanURL(req.URL)),
+		),
+	)
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	req = req.WithContext(ctx)
+	tracing.Inject(ctx, req)
+
 	res, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.New("goreg: bad status code: " + res.Status)
-	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
 
 	bodyBytes, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		statusErr := &StatusError{
+			Code:   res.StatusCode,
+			Status: res.Status,
+			Body:   strings.TrimSpace(string(bodyBytes)),
+		}
+
+		var problem struct {
+			Detail string `json:"detail"`
+			Code   string `json:"code"`
+		}
+		if strings.HasPrefix(res.Header.Get("Content-Type"), "application/problem+json") &&
+			json.Unmarshal(bodyBytes, &problem) == nil {
+			statusErr.Body = problem.Detail
+			statusErr.Problem = problem.Code
+		}
+		return nil, res.Header, statusErr
 	}
 
-	return bodyBytes, nil
+	return bodyBytes, res.Header, nil
 }
@@ -5,20 +5,20 @@ import (
 	"github.com/Danis0n/goreg/internal/goreg/server"
 )
 
-func NewGoregClient(cfg client.ClientConfig) (*client.Client, error) {
-	return client.NewClient(cfg)
+func NewGoregClient(cfg client.ClientConfig, opts ...client.Option) (*client.Client, error) {
+	return client.NewClient(cfg, opts...)
 }
 
-func NewGoregClientWithStart(cfg client.ClientConfig) (*client.Client, error) {
-	return client.NewClientWithStart(cfg)
+func NewGoregClientWithStart(cfg client.ClientConfig, opts ...client.Option) (*client.Client, error) {
+	return client.NewClientWithStart(cfg, opts...)
 }
 
-func NewGoregServer(cfg server.ServerConfig) (*server.Server, error) {
-	return server.NewServer(cfg)
+func NewGoregServer(cfg server.ServerConfig, opts ...server.Option) (*server.Server, error) {
+	return server.NewServer(cfg, opts...)
 }
 
-func NewGoregServerWithStart(cfg server.ServerConfig) (*server.Server, error) {
-	return server.NewServerWithStart(cfg)
+func NewGoregServerWithStart(cfg server.ServerConfig, opts ...server.Option) (*server.Server, error) {
+	return server.NewServerWithStart(cfg, opts...)
 }
 
 func NewGoregClientConfig(registrator, callback, name string, port int) (client.ClientConfig, error) {
@@ -0,0 +1,141 @@
+// Package openapitest checks HTTP exchanges against the registry OpenAPI
+// document. It is used by the server and client contract tests.
+package openapitest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// JSON lines exports, such as the audit log, are checked as plain text.
+func init() {
+	openapi3filter.RegisterBodyDecoder("application/x-ndjson", openapi3filter.RegisteredBodyDecoder("text/plain"))
+}
+
+// Spec is the parsed and validated server.OpenAPI document.
+type Spec struct {
+	Doc    *openapi3.T
+	router routers.Router
+
+	mu      sync.Mutex
+	covered map[string]bool
+}
+
+func Load(t testing.TB) *Spec {
+	t.Helper()
+
+	ctx := context.Background()
+	doc, err := openapi3.NewLoader().LoadFromData(server.OpenAPI)
+	if err != nil {
+		t.Fatalf("openapi: load: %v", err)
+	}
+	if err := doc.Validate(ctx); err != nil {
+		t.Fatalf("openapi: invalid document: %v", err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("openapi: router: %v", err)
+	}
+
+	return &Spec{Doc: doc, router: router, covered: make(map[string]bool)}
+}
+
+// Middleware fails t for every request or response of next that does not
+// match the document, including undocumented routes and status codes.
+func (s *Spec) Middleware(t testing.TB, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		rr := httptest.NewRecorder()
+		next.ServeHTTP(rr, r.Clone(r.Context()))
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		s.check(t, r, rr)
+
+		for key, values := range rr.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rr.Code)
+		w.Write(rr.Body.Bytes())
+	})
+}
+
+// Serve runs req through handler and checks the exchange.
+func (s *Spec) Serve(t testing.TB, handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	s.Middleware(t, handler).ServeHTTP(rr, req)
+	return rr
+}
+
+// Uncovered lists the documented operations no checked exchange used.
+func (s *Spec) Uncovered() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var missing []string
+	for path, item := range s.Doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !s.covered[method+" "+path] {
+				missing = append(missing, method+" "+path)
+			}
+		}
+	}
+	return missing
+}
+
+func (s *Spec) check(t testing.TB, r *http.Request, rr *httptest.ResponseRecorder) {
+	t.Helper()
+
+	route, params, err := s.router.FindRoute(r)
+	if err != nil {
+		t.Errorf("openapi: %s %s is not documented: %v", r.Method, r.URL.Path, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.covered[r.Method+" "+route.Path] = true
+	s.mu.Unlock()
+
+	options := &openapi3filter.Options{
+		IncludeResponseStatus: true,
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+	}
+	input := &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: params,
+		Route:      route,
+		Options:    options,
+	}
+
+	ctx := context.Background()
+	if err := openapi3filter.ValidateRequest(ctx, input); err != nil {
+		t.Errorf("openapi: request %s %s does not match: %v", r.Method, r.URL, err)
+	}
+
+	response := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 rr.Code,
+		Header:                 rr.Header(),
+		Options:                options,
+	}
+	response.SetBodyBytes(rr.Body.Bytes())
+
+	if err := openapi3filter.ValidateResponse(ctx, response); err != nil {
+		t.Errorf("openapi: response %d to %s %s does not match: %v", rr.Code, r.Method, r.URL, err)
+	}
+}
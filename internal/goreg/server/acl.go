@@ -0,0 +1,302 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Capability string
+
+const (
+	CapabilityRead       Capability = "read"
+	CapabilityRegister   Capability = "register"
+	CapabilityDeregister Capability = "deregister"
+	// CapabilityAdmin implies every other capability and, on a rule matching
+	// "*", grants access to the policy management endpoint.
+	CapabilityAdmin Capability = "admin"
+)
+
+const (
+	ACLPolicyAllow = "allow"
+	ACLPolicyDeny  = "deny"
+
+	tokenHeader = "X-Goreg-Token"
+)
+
+type ACLRule struct {
+	Service      string       `yaml:"service" json:"service"`
+	Capabilities []Capability `yaml:"capabilities" json:"capabilities"`
+	Deny         bool         `yaml:"deny" json:"deny,omitempty"`
+}
+
+type ACLPolicy struct {
+	Name  string    `yaml:"name" json:"name"`
+	Rules []ACLRule `yaml:"rules" json:"rules"`
+}
+
+// ACLToken never encodes its secret to JSON; UnmarshalJSON still reads it so
+// an ACLConfig can be decoded from JSON.
+type ACLToken struct {
+	ID       string   `yaml:"id" json:"id"`
+	Secret   string   `yaml:"secret" json:"-"`
+	Policies []string `yaml:"policies" json:"policies"`
+}
+
+func (t *ACLToken) UnmarshalJSON(data []byte) error {
+	type token ACLToken
+	var raw struct {
+		token
+		Secret string `json:"secret"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	*t = ACLToken(raw.token)
+	t.Secret = raw.Secret
+	return nil
+}
+
+type ACLConfig struct {
+	Enabled       bool        `yaml:"enabled" json:"enabled"`
+	DefaultPolicy string      `yaml:"default_policy" json:"default_policy"`
+	Policies      []ACLPolicy `yaml:"policies" json:"policies"`
+	Tokens        []ACLToken  `yaml:"tokens" json:"tokens"`
+}
+
+type ACL struct {
+	rwmu         *sync.RWMutex
+	defaultAllow bool
+	policies     map[string]*ACLPolicy
+	tokens       map[string]*ACLToken
+	// generation counts policy changes, which change what tokens may read
+	// without bumping the store index.
+	generation uint64
+}
+
+func NewACL(cfg ACLConfig) (*ACL, error) {
+	if err := ValidateACLConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	acl := &ACL{
+		rwmu:         &sync.RWMutex{},
+		defaultAllow: cfg.DefaultPolicy == ACLPolicyAllow,
+		policies:     make(map[string]*ACLPolicy, len(cfg.Policies)),
+		tokens:       make(map[string]*ACLToken, len(cfg.Tokens)),
+	}
+
+	for i := range cfg.Policies {
+		policy := cfg.Policies[i]
+		acl.policies[policy.Name] = &policy
+	}
+
+	for i := range cfg.Tokens {
+		token := cfg.Tokens[i]
+		acl.tokens[token.Secret] = &token
+	}
+
+	return acl, nil
+}
+
+func ValidateACLConfig(cfg ACLConfig) error {
+	var errs []error
+
+	switch cfg.DefaultPolicy {
+	case "", ACLPolicyAllow, ACLPolicyDeny:
+	default:
+		errs = append(errs, errors.New("acl default policy invalid"))
+	}
+
+	names := make(map[string]bool, len(cfg.Policies))
+	for _, policy := range cfg.Policies {
+		if err := ValidateACLPolicy(policy); err != nil {
+			errs = append(errs, err)
+		}
+		names[policy.Name] = true
+	}
+
+	secrets := make(map[string]bool, len(cfg.Tokens))
+	for _, token := range cfg.Tokens {
+		if token.ID == "" || token.Secret == "" {
+			errs = append(errs, errors.New("acl token invalid"))
+			continue
+		}
+		if secrets[token.Secret] {
+			errs = append(errs, errors.New("acl token {"+token.ID+"} secret is duplicated"))
+		}
+		secrets[token.Secret] = true
+
+		for _, name := range token.Policies {
+			if !names[name] {
+				errs = append(errs, errors.New("acl token {"+token.ID+"} references unknown policy {"+name+"}"))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func ValidateACLPolicy(policy ACLPolicy) error {
+	if policy.Name == "" {
+		return errors.New("acl policy name invalid")
+	}
+
+	for _, rule := range policy.Rules {
+		if _, err := path.Match(rule.Service, ""); err != nil {
+			return errors.New("acl policy {" + policy.Name + "} rule pattern invalid: " + rule.Service)
+		}
+
+		for _, capability := range rule.Capabilities {
+			switch capability {
+			case CapabilityRead, CapabilityRegister, CapabilityDeregister, CapabilityAdmin:
+			default:
+				return errors.New("acl policy {" + policy.Name + "} capability invalid: " + string(capability))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Authorize reports whether the token identified by secret holds capability on
+// service. Explicit deny rules win over grants; when no rule matches the
+// default policy decides, except for CapabilityAdmin which only a rule grants.
+func (a *ACL) Authorize(secret, service string, capability Capability) bool {
+	a.rwmu.RLock()
+	defer a.rwmu.RUnlock()
+
+	token, ok := a.tokens[secret]
+	if !ok {
+		return a.fallback(capability)
+	}
+
+	granted := false
+	for _, name := range token.Policies {
+		policy, ok := a.policies[name]
+		if !ok {
+			continue
+		}
+
+		for _, rule := range policy.Rules {
+			if matched, _ := path.Match(rule.Service, service); !matched {
+				continue
+			}
+
+			if !rule.grants(capability) {
+				continue
+			}
+
+			if rule.Deny {
+				return false
+			}
+			granted = true
+		}
+	}
+
+	if granted {
+		return true
+	}
+
+	return a.fallback(capability)
+}
+
+func (a *ACL) fallback(capability Capability) bool {
+	return a.defaultAllow && capability != CapabilityAdmin
+}
+
+func (a *ACL) Token(secret string) (*ACLToken, bool) {
+	a.rwmu.RLock()
+	defer a.rwmu.RUnlock()
+
+	token, ok := a.tokens[secret]
+	return token, ok
+}
+
+func (a *ACL) Policies() []ACLPolicy {
+	a.rwmu.RLock()
+	defer a.rwmu.RUnlock()
+
+	policies := make([]ACLPolicy, 0, len(a.policies))
+	for _, policy := range a.policies {
+		policies = append(policies, *policy)
+	}
+
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+
+	return policies
+}
+
+func (a *ACL) SetPolicy(policy ACLPolicy) error {
+	if err := ValidateACLPolicy(policy); err != nil {
+		return err
+	}
+
+	a.rwmu.Lock()
+	defer a.rwmu.Unlock()
+
+	a.policies[policy.Name] = &policy
+	a.generation++
+	return nil
+}
+
+func (a *ACL) DeletePolicy(name string) error {
+	a.rwmu.Lock()
+	defer a.rwmu.Unlock()
+
+	if _, ok := a.policies[name]; !ok {
+		return errors.New("goreg->[acl]: policy {" + name + "} doesn't exists")
+	}
+
+	for _, token := range a.tokens {
+		for _, policy := range token.Policies {
+			if policy == name {
+				return errors.New("goreg->[acl]: policy {" + name + "} is used by token {" + token.ID + "}")
+			}
+		}
+	}
+
+	delete(a.policies, name)
+	a.generation++
+	return nil
+}
+
+func (a *ACL) Generation() uint64 {
+	a.rwmu.RLock()
+	defer a.rwmu.RUnlock()
+
+	return a.generation
+}
+
+func (r ACLRule) grants(capability Capability) bool {
+	for _, c := range r.Capabilities {
+		if c == capability || c == CapabilityAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func RequestToken(r *http.Request) string {
+	if token := r.Header.Get(tokenHeader); token != "" {
+		return token
+	}
+
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+
+	return ""
+}
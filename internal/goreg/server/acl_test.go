@@ -0,0 +1,254 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testACLConfig() ACLConfig {
+	return ACLConfig{
+		Enabled:       true,
+		DefaultPolicy: ACLPolicyDeny,
+		Policies: []ACLPolicy{
+			{
+				Name: "payments",
+				Rules: []ACLRule{
+					{Service: "payments-*", Capabilities: []Capability{CapabilityRead, CapabilityRegister}},
+					{Service: "payments-ledger", Capabilities: []Capability{CapabilityRegister}, Deny: true},
+				},
+			},
+			{
+				Name:  "read-only",
+				Rules: []ACLRule{{Service: "*", Capabilities: []Capability{CapabilityRead}}},
+			},
+			{
+				Name:  "admin",
+				Rules: []ACLRule{{Service: "*", Capabilities: []Capability{CapabilityAdmin}}},
+			},
+		},
+		Tokens: []ACLToken{
+			{ID: "payments", Secret: "payments-secret", Policies: []string{"payments"}},
+			{ID: "reader", Secret: "reader-secret", Policies: []string{"read-only"}},
+			{ID: "root", Secret: "root-secret", Policies: []string{"admin"}},
+		},
+	}
+}
+
+func TestACL_Authorize(t *testing.T) {
+	acl, err := NewACL(testACLConfig())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		secret     string
+		service    string
+		capability Capability
+		want       bool
+	}{
+		{"Prefix grant", "payments-secret", "payments-api", CapabilityRegister, true},
+		{"Prefix read", "payments-secret", "payments-api", CapabilityRead, true},
+		{"Outside prefix", "payments-secret", "orders", CapabilityRegister, false},
+		{"Missing capability", "payments-secret", "payments-api", CapabilityDeregister, false},
+		{"Explicit deny wins", "payments-secret", "payments-ledger", CapabilityRegister, false},
+		{"Read-only reads", "reader-secret", "orders", CapabilityRead, true},
+		{"Read-only cannot register", "reader-secret", "orders", CapabilityRegister, false},
+		{"Admin implies all", "root-secret", "orders", CapabilityDeregister, true},
+		{"Admin management", "root-secret", "*", CapabilityAdmin, true},
+		{"Non-admin management", "reader-secret", "*", CapabilityAdmin, false},
+		{"Anonymous denied", "", "orders", CapabilityRead, false},
+		{"Unknown token denied", "nope", "orders", CapabilityRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acl.Authorize(tt.secret, tt.service, tt.capability); got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestACL_DefaultAllow(t *testing.T) {
+	cfg := testACLConfig()
+	cfg.DefaultPolicy = ACLPolicyAllow
+
+	acl, err := NewACL(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !acl.Authorize("", "orders", CapabilityRegister) {
+		t.Errorf("expected anonymous register to be allowed")
+	}
+
+	if acl.Authorize("payments-secret", "payments-ledger", CapabilityRegister) {
+		t.Errorf("expected explicit deny to win over default allow")
+	}
+
+	if acl.Authorize("", "*", CapabilityAdmin) {
+		t.Errorf("expected default allow not to grant admin to anonymous callers")
+	}
+
+	if acl.Authorize("reader-secret", "*", CapabilityAdmin) {
+		t.Errorf("expected default allow not to grant admin without an admin rule")
+	}
+
+	if !acl.Authorize("root-secret", "*", CapabilityAdmin) {
+		t.Errorf("expected admin rule to grant admin")
+	}
+}
+
+func TestPoliciesHandler_DefaultAllow(t *testing.T) {
+	cfg := testACLConfig()
+	cfg.DefaultPolicy = ACLPolicyAllow
+
+	server := setupTestServer()
+	server.acl, _ = NewACL(cfg)
+
+	body, _ := json.Marshal(ACLPolicy{
+		Name:  "payments",
+		Rules: []ACLRule{{Service: "*", Capabilities: []Capability{CapabilityAdmin}}},
+	})
+
+	req, _ := http.NewRequest(http.MethodPut, "/acl/policies", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	server.PoliciesHandler(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	if rules := server.acl.Policies()[1].Rules; rules[0].Capabilities[0] != CapabilityRead {
+		t.Errorf("expected payments policy to be unchanged, got %v", rules)
+	}
+}
+
+func TestACLToken_JSON(t *testing.T) {
+	var token ACLToken
+	if err := json.Unmarshal([]byte(`{"id":"root","secret":"root-secret","policies":["admin"]}`), &token); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if token.ID != "root" || token.Secret != "root-secret" || len(token.Policies) != 1 {
+		t.Errorf("unexpected token: %+v", token)
+	}
+
+	data, err := json.Marshal(ACLConfig{Tokens: []ACLToken{token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte("root-secret")) {
+		t.Errorf("expected secret not to be encoded, got %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"id":"root","secrte":"root-secret"}`), &token); err == nil {
+		t.Errorf("expected error for unknown field, got nil")
+	}
+}
+
+func TestValidateACLConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutate    func(cfg *ACLConfig)
+		wantError bool
+	}{
+		{"Valid config", func(cfg *ACLConfig) {}, false},
+		{"Invalid default policy", func(cfg *ACLConfig) { cfg.DefaultPolicy = "maybe" }, true},
+		{"Unknown policy", func(cfg *ACLConfig) { cfg.Tokens[0].Policies = []string{"missing"} }, true},
+		{"Duplicated secret", func(cfg *ACLConfig) { cfg.Tokens[1].Secret = cfg.Tokens[0].Secret }, true},
+		{"Invalid capability", func(cfg *ACLConfig) { cfg.Policies[0].Rules[0].Capabilities = []Capability{"write"} }, true},
+		{"Invalid pattern", func(cfg *ACLConfig) { cfg.Policies[0].Rules[0].Service = "[" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testACLConfig()
+			tt.mutate(&cfg)
+
+			err := ValidateACLConfig(cfg)
+			if (err != nil) != tt.wantError {
+				t.Errorf("ValidateACLConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestACL_Handlers(t *testing.T) {
+	server := setupTestServer()
+	server.acl, _ = NewACL(testACLConfig())
+
+	body, _ := json.Marshal(Service{Name: "orders", Callback: "http://callback.url"})
+	req, _ := http.NewRequest(http.MethodPost, "/set", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer payments-secret")
+	rr := httptest.NewRecorder()
+	server.SetHandler(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	server.store.Set("payments-api", "http://callback1.url")
+	server.store.Set("orders", "http://callback2.url")
+
+	req, _ = http.NewRequest(http.MethodGet, "/getall", nil)
+	req.Header.Set(tokenHeader, "payments-secret")
+	rr = httptest.NewRecorder()
+	server.GetAllHandler(rr, req)
+
+	var services []*Service
+	if err := json.NewDecoder(rr.Body).Decode(&services); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(services) != 1 || services[0].Name != "payments-api" {
+		t.Errorf("expected only readable services, got %v", services)
+	}
+}
+
+func TestPoliciesHandler(t *testing.T) {
+	server := setupTestServer()
+	server.acl, _ = NewACL(testACLConfig())
+
+	policy := ACLPolicy{
+		Name:  "orders",
+		Rules: []ACLRule{{Service: "orders", Capabilities: []Capability{CapabilityRegister}}},
+	}
+	body, _ := json.Marshal(policy)
+
+	req, _ := http.NewRequest(http.MethodPut, "/acl/policies", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer reader-secret")
+	rr := httptest.NewRecorder()
+	server.PoliciesHandler(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, "/acl/policies", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer root-secret")
+	rr = httptest.NewRecorder()
+	server.PoliciesHandler(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, "/acl/policies?name=payments", nil)
+	req.Header.Set("Authorization", "Bearer root-secret")
+	rr = httptest.NewRecorder()
+	server.PoliciesHandler(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected policy in use to be rejected, got %v", rr.Code)
+	}
+
+	if len(server.acl.Policies()) != 4 {
+		t.Errorf("expected 4 policies, got %v", len(server.acl.Policies()))
+	}
+}
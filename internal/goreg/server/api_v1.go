@@ -0,0 +1,321 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The /v1 API addresses registrations as resources: a service name owns its
+// instances, identified by the registration hash.
+const (
+	v1Services  = "/v1/services"
+	v1Service   = "/v1/services/{name}"
+	v1Instances = "/v1/services/{name}/instances"
+	v1Instance  = "/v1/services/{name}/instances/{id}"
+	v1History   = "/v1/services/{name}/instances/{id}/history"
+)
+
+func (g *Server) registerV1(mux *http.ServeMux) {
+	g.handle(mux, v1Services, g.ServicesV1Handler)
+	g.handle(mux, v1Service, g.ServiceV1Handler)
+	g.handle(mux, v1Instances, g.InstancesV1Handler)
+	g.handle(mux, v1Instance, g.InstanceV1Handler)
+	g.handle(mux, v1History, g.HistoryV1Handler)
+	g.handle(mux, "/v1/flapping", g.FlappingV1Handler)
+	g.handle(mux, "/v1/audit", g.AuditV1Handler)
+	g.handle(mux, "/v1/batch", g.BatchV1Handler)
+	g.handle(mux, "/v1/", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, CodeNotFound, "no such resource")
+	})
+}
+
+// ServicesV1Handler lists services (GET, optionally ?status=) and registers
+// new ones (POST).
+func (g *Server) ServicesV1Handler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		index, err := g.blockingQuery(w, r)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+			return
+		}
+
+		status := HealthStatus(r.URL.Query().Get("status"))
+
+		services := g.readable(r, g.store.GetAll())
+		filtered := make([]*Service, 0, len(services))
+		for _, service := range services {
+			if status == "" || service.Status == status {
+				filtered = append(filtered, service)
+			}
+		}
+		sort.Slice(filtered, func(i, j int) bool {
+			return filtered[i].Name < filtered[j].Name
+		})
+
+		g.setListETag(w, index)
+		if notModified(w, r) {
+			return
+		}
+		writeJSON(w, http.StatusOK, filtered)
+	case http.MethodPost:
+		g.registerServiceV1(w, r)
+	default:
+		writeMethodNotAllowed(w, r, "GET, POST")
+	}
+}
+
+func (g *Server) registerServiceV1(w http.ResponseWriter, r *http.Request) {
+	var svc Service
+	if err := json.NewDecoder(r.Body).Decode(&svc); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "body is not a valid service: "+err.Error())
+		return
+	}
+	svc = advertised(svc, g.httpCaller(r))
+
+	if err := validateService(svc); err != nil {
+		writeValidationProblem(w, r, err)
+		return
+	}
+
+	if !g.allowedV1(w, r, svc.Name, CapabilityRegister) {
+		return
+	}
+
+	if err := g.store.SetService(svc); err != nil {
+		if errors.Is(err, ErrServiceExists) {
+			writeProblem(w, r, http.StatusConflict, CodeServiceExists, "service "+svc.Name+" is already registered")
+			return
+		}
+		writeProblem(w, r, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	g.metrics.registered()
+
+	service, err := g.store.Get(svc.Name)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	g.record(g.httpCaller(r), AuditRegister, nil, service)
+
+	w.Header().Set("Location", v1Services+"/"+service.Name+"/instances/"+service.Hash)
+	writeJSON(w, http.StatusCreated, service)
+}
+
+// ServiceV1Handler reads (GET), updates (PUT) or deregisters (DELETE) a
+// service.
+func (g *Server) ServiceV1Handler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		if service, ok := g.lookupV1(w, r, name, CapabilityRead); ok {
+			setETag(w, service)
+			if notModified(w, r) {
+				return
+			}
+			writeJSON(w, http.StatusOK, service)
+		}
+	case http.MethodPut:
+		g.updateServiceV1(w, r, name)
+	case http.MethodDelete:
+		if _, ok := g.lookupV1(w, r, name, CapabilityDeregister); ok {
+			g.deregisterV1(w, r, name, "")
+		}
+	default:
+		writeMethodNotAllowed(w, r, "GET, PUT, DELETE")
+	}
+}
+
+// updateServiceV1 replaces the mutable fields of a registration. Writes can
+// be made conditional with If-Match or ?cas=<modify_index>.
+func (g *Server) updateServiceV1(w http.ResponseWriter, r *http.Request, name string) {
+	cas, ifMatch, err := precondition(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, err.Error())
+		return
+	}
+
+	var svc Service
+	if err := json.NewDecoder(r.Body).Decode(&svc); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "body is not a valid service: "+err.Error())
+		return
+	}
+
+	if svc.Name == "" {
+		svc.Name = name
+	}
+	if svc.Name != name {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "body names "+svc.Name+", path names "+name)
+		return
+	}
+	svc = advertised(svc, g.httpCaller(r))
+
+	if err := validateService(svc); err != nil {
+		writeValidationProblem(w, r, err)
+		return
+	}
+
+	if !g.allowedV1(w, r, name, CapabilityRegister) {
+		return
+	}
+
+	before, service, err := g.update(svc, cas)
+	switch {
+	case errors.Is(err, ErrServiceNotFound):
+		writeProblem(w, r, http.StatusNotFound, CodeServiceNotFound, "service "+name+" is not registered")
+		return
+	case errors.Is(err, ErrIndexMismatch) && ifMatch:
+		writeProblem(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, "service "+name+" was modified since index "+strconv.FormatUint(cas, 10))
+		return
+	case errors.Is(err, ErrIndexMismatch):
+		writeProblem(w, r, http.StatusConflict, CodeIndexConflict, "service "+name+" was modified since index "+strconv.FormatUint(cas, 10))
+		return
+	case err != nil:
+		writeProblem(w, r, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	g.record(g.httpCaller(r), AuditUpdate, before, service)
+
+	setETag(w, service)
+	writeJSON(w, http.StatusOK, service)
+}
+
+// InstancesV1Handler lists the instances registered under a service name.
+func (g *Server) InstancesV1Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, "GET")
+		return
+	}
+
+	if service, ok := g.lookupV1(w, r, r.PathValue("name"), CapabilityRead); ok {
+		setETag(w, service)
+		if notModified(w, r) {
+			return
+		}
+		writeJSON(w, http.StatusOK, []*Service{service})
+	}
+}
+
+// InstanceV1Handler reads (GET) or deregisters (DELETE) one instance.
+func (g *Server) InstanceV1Handler(w http.ResponseWriter, r *http.Request) {
+	name, id := r.PathValue("name"), r.PathValue("id")
+
+	capability := CapabilityRead
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		capability = CapabilityDeregister
+	default:
+		writeMethodNotAllowed(w, r, "GET, DELETE")
+		return
+	}
+
+	service, ok := g.lookupV1(w, r, name, capability)
+	if !ok {
+		return
+	}
+
+	if service.Hash != id {
+		writeProblem(w, r, http.StatusNotFound, CodeInstanceNotFound, "service "+name+" has no instance "+id)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		g.deregisterV1(w, r, name, id)
+		return
+	}
+	setETag(w, service)
+	if notModified(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, service)
+}
+
+// lookupV1 authorizes the request for capability and fetches the service,
+// writing the problem when either fails. GET lookups may be blocking queries.
+func (g *Server) lookupV1(w http.ResponseWriter, r *http.Request, name string, capability Capability) (*Service, bool) {
+	if !g.allowedV1(w, r, name, capability) {
+		return nil, false
+	}
+
+	if r.Method == http.MethodGet {
+		if _, err := g.blockingQuery(w, r); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+			return nil, false
+		}
+	}
+
+	service, err := g.store.Get(name)
+	if err != nil {
+		writeProblem(w, r, http.StatusNotFound, CodeServiceNotFound, "service "+name+" is not registered")
+		return nil, false
+	}
+	return service, true
+}
+
+// deregisterV1 removes the registration of name, or only the instance id when
+// it is set, so a newer registration of the name is kept.
+func (g *Server) deregisterV1(w http.ResponseWriter, r *http.Request, name, id string) {
+	removed, err := g.store.RemoveInstance(name, id)
+	switch {
+	case err != nil && id != "":
+		writeProblem(w, r, http.StatusNotFound, CodeInstanceNotFound, "service "+name+" has no instance "+id)
+		return
+	case err != nil:
+		writeProblem(w, r, http.StatusNotFound, CodeServiceNotFound, "service "+name+" is not registered")
+		return
+	}
+	g.metrics.deregistered()
+	g.record(g.httpCaller(r), AuditDeregister, removed, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *Server) allowedV1(w http.ResponseWriter, r *http.Request, service string, capability Capability) bool {
+	if g.allowed(r, service, capability) {
+		return true
+	}
+
+	writeProblem(w, r, http.StatusForbidden, CodePermissionDenied, "token lacks "+string(capability)+" on "+service)
+	return false
+}
+
+// precondition returns the compare-and-set index of a write, taken from
+// If-Match or the cas query parameter. ifMatch reports which one was used, as
+// a stale If-Match fails with 412 and a stale cas with 409. "If-Match: *"
+// only requires the service to exist.
+func precondition(r *http.Request) (index uint64, ifMatch bool, err error) {
+	if header := r.Header.Get("If-Match"); header != "" {
+		if header == "*" {
+			return 0, true, nil
+		}
+
+		index, err = strconv.ParseUint(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+		if err != nil || index == 0 {
+			return 0, true, errors.New("If-Match must be a modify index etag")
+		}
+		return index, true, nil
+	}
+
+	if raw := r.URL.Query().Get("cas"); raw != "" {
+		index, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return 0, false, errors.New("cas must be a modify index")
+		}
+		return index, false, nil
+	}
+
+	return 0, false, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
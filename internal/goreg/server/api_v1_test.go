@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveV1(t *testing.T, handler http.Handler, method, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(method, target, &buf))
+	return rr
+}
+
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) Problem {
+	t.Helper()
+
+	if ct := rr.Header().Get("Content-Type"); ct != problemContentType {
+		t.Fatalf("expected %s, got %s", problemContentType, ct)
+	}
+
+	var problem Problem
+	if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	return problem
+}
+
+func TestV1_ServiceLifecycle(t *testing.T) {
+	handler := setupTestServer().Handler()
+
+	rr := serveV1(t, handler, http.MethodPost, "/v1/services", Service{Name: "orders", Callback: "http://orders:8080/callback"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("register returned %d: %s", rr.Code, rr.Body)
+	}
+
+	var created Service
+	json.NewDecoder(rr.Body).Decode(&created)
+	if want := "/v1/services/orders/instances/" + created.Hash; rr.Header().Get("Location") != want {
+		t.Errorf("unexpected location %q, want %q", rr.Header().Get("Location"), want)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		code   int
+	}{
+		{"list", http.MethodGet, "/v1/services", http.StatusOK},
+		{"list by status", http.MethodGet, "/v1/services?status=passing", http.StatusOK},
+		{"get", http.MethodGet, "/v1/services/orders", http.StatusOK},
+		{"instances", http.MethodGet, "/v1/services/orders/instances", http.StatusOK},
+		{"instance", http.MethodGet, "/v1/services/orders/instances/" + created.Hash, http.StatusOK},
+		{"unknown instance", http.MethodGet, "/v1/services/orders/instances/other", http.StatusNotFound},
+		{"delete instance", http.MethodDelete, "/v1/services/orders/instances/" + created.Hash, http.StatusNoContent},
+		{"get deleted", http.MethodGet, "/v1/services/orders", http.StatusNotFound},
+		{"delete deleted", http.MethodDelete, "/v1/services/orders", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveV1(t, handler, tt.method, tt.target, nil)
+			if rr.Code != tt.code {
+				t.Errorf("%s %s returned %d, want %d", tt.method, tt.target, rr.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestV1_DeleteReplacedInstance(t *testing.T) {
+	server := setupTestServer()
+	server.store.Set("orders", "http://orders:8080/callback")
+	old, _ := server.store.Get("orders")
+
+	// The instance re-registers between the lookup and the removal.
+	server.store.Delete("orders")
+	server.store.Set("orders", "http://orders:8080/callback")
+	newer, _ := server.store.Get("orders")
+
+	rr := httptest.NewRecorder()
+	server.deregisterV1(rr, httptest.NewRequest(http.MethodDelete, "/v1/services/orders/instances/"+old.Hash, nil), "orders", old.Hash)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("delete returned %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	if problem := decodeProblem(t, rr); problem.Code != CodeInstanceNotFound {
+		t.Errorf("unexpected problem code %q", problem.Code)
+	}
+
+	current, err := server.store.Get("orders")
+	if err != nil || current.Hash != newer.Hash {
+		t.Errorf("expected newer instance to be kept, got %v %v", current, err)
+	}
+}
+
+func TestV1_Problems(t *testing.T) {
+	server := setupTestServer()
+	server.store.Set("orders", "http://orders:8080/callback")
+	handler := server.Handler()
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		status int
+		code   string
+	}{
+		{"invalid body", http.MethodPost, "/v1/services", "not a service", http.StatusBadRequest, CodeInvalidBody},
+		{"validation", http.MethodPost, "/v1/services", Service{Name: "-bad", Callback: "ftp://x"}, http.StatusBadRequest, CodeValidationFailed},
+		{"exists", http.MethodPost, "/v1/services", Service{Name: "orders", Callback: "http://orders"}, http.StatusConflict, CodeServiceExists},
+		{"not found", http.MethodGet, "/v1/services/billing", nil, http.StatusNotFound, CodeServiceNotFound},
+		{"method", http.MethodPatch, "/v1/services/orders", nil, http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{"unknown route", http.MethodGet, "/v1/nodes", nil, http.StatusNotFound, CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveV1(t, handler, tt.method, tt.target, tt.body)
+			if rr.Code != tt.status {
+				t.Fatalf("unexpected status: got %d want %d", rr.Code, tt.status)
+			}
+
+			problem := decodeProblem(t, rr)
+			if problem.Code != tt.code || problem.Status != tt.status || problem.Type != "urn:goreg:problem:"+tt.code {
+				t.Errorf("unexpected problem: %+v", problem)
+			}
+		})
+	}
+}
+
+func TestV1_UpdatePreconditions(t *testing.T) {
+	server := setupTestServer()
+	server.store.Set("orders", "http://orders:8080/callback")
+	handler := server.Handler()
+
+	rr := serveV1(t, handler, http.MethodGet, "/v1/services/orders", nil)
+	etag := rr.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("unexpected etag %q", etag)
+	}
+
+	update := Service{Callback: "http://orders:9090/callback", Tags: []string{"v2"}}
+
+	tests := []struct {
+		name    string
+		target  string
+		ifMatch string
+		status  int
+		code    string
+	}{
+		{"if-match", "/v1/services/orders", etag, http.StatusOK, ""},
+		{"stale if-match", "/v1/services/orders", etag, http.StatusPreconditionFailed, CodePreconditionFailed},
+		{"stale cas", "/v1/services/orders?cas=1", "", http.StatusConflict, CodeIndexConflict},
+		{"cas", "/v1/services/orders?cas=2", "", http.StatusOK, ""},
+		{"any", "/v1/services/orders", "*", http.StatusOK, ""},
+		{"unconditional", "/v1/services/orders", "", http.StatusOK, ""},
+		{"invalid if-match", "/v1/services/orders", `"abc"`, http.StatusBadRequest, CodeInvalidBody},
+		{"unknown", "/v1/services/billing", "*", http.StatusNotFound, CodeServiceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(update)
+			req := httptest.NewRequest(http.MethodPut, tt.target, bytes.NewReader(body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("unexpected status: got %d want %d", rr.Code, tt.status)
+			}
+			if tt.code != "" {
+				if problem := decodeProblem(t, rr); problem.Code != tt.code {
+					t.Errorf("unexpected problem code %q", problem.Code)
+				}
+				return
+			}
+			if rr.Header().Get("ETag") == "" {
+				t.Error("expected an etag on the updated service")
+			}
+		})
+	}
+
+	service, _ := server.store.Get("orders")
+	if service.Callback != update.Callback || service.ModifyIndex != 5 || service.CreateIndex != 1 {
+		t.Errorf("unexpected service after updates: %+v", service)
+	}
+}
+
+func TestV1_ValidationProblemFields(t *testing.T) {
+	handler := setupTestServer().Handler()
+
+	rr := serveV1(t, handler, http.MethodPost, "/v1/services", Service{Name: "-bad", Callback: "ftp://x", Port: 70000})
+	problem := decodeProblem(t, rr)
+
+	codes := make(map[string]string)
+	for _, param := range problem.InvalidParams {
+		codes[param.Name] = param.Code
+	}
+
+	want := map[string]string{"name": "invalid_name", "callback": "unsupported_scheme", "port": "out_of_range"}
+	for field, code := range want {
+		if codes[field] != code {
+			t.Errorf("expected %s for %s, got %q", code, field, codes[field])
+		}
+	}
+}
+
+func TestV1_PermissionDenied(t *testing.T) {
+	server := setupTestServer()
+	acl, err := NewACL(ACLConfig{Enabled: true, DefaultPolicy: ACLPolicyDeny})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.acl = acl
+
+	rr := serveV1(t, server.Handler(), http.MethodPost, "/v1/services", Service{Name: "orders", Callback: "http://orders"})
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status %d", rr.Code)
+	}
+	if problem := decodeProblem(t, rr); problem.Code != CodePermissionDenied {
+		t.Errorf("unexpected problem code %q", problem.Code)
+	}
+}
+
+func TestLegacyRoutesDeprecated(t *testing.T) {
+	handler := setupTestServer().Handler()
+
+	rr := serveV1(t, handler, http.MethodGet, "/getall", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("legacy route returned %d", rr.Code)
+	}
+	if rr.Header().Get("Deprecation") != "true" || rr.Header().Get("Link") != `</v1/services>; rel="successor-version"` {
+		t.Errorf("missing deprecation headers: %v", rr.Header())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		link   string
+	}{
+		{"Get with name", http.MethodGet, "/get?name=orders", `</v1/services/orders>; rel="successor-version"`},
+		{"Delete with name", http.MethodDelete, "/delete?name=orders", `</v1/services/orders>; rel="successor-version"`},
+		{"Escaped name", http.MethodGet, "/get?name=a%2Fb", `</v1/services/a%2Fb>; rel="successor-version"`},
+		{"Get without name", http.MethodGet, "/get", ""},
+		{"Delete without name", http.MethodDelete, "/delete", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveV1(t, handler, tt.method, tt.target, nil)
+			if rr.Header().Get("Deprecation") != "true" {
+				t.Errorf("missing deprecation header: %v", rr.Header())
+			}
+			if link := rr.Header().Get("Link"); link != tt.link {
+				t.Errorf("Link = %q, want %q", link, tt.link)
+			}
+		})
+	}
+}
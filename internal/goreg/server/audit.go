@@ -0,0 +1,298 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/peer"
+)
+
+type AuditAction string
+
+const (
+	AuditRegister   AuditAction = "register"
+	AuditUpdate     AuditAction = "update"
+	AuditDeregister AuditAction = "deregister"
+	AuditEvict      AuditAction = "evict"
+	AuditHealth     AuditAction = "health"
+)
+
+// Actors of audit entries: the API a change came through, or the registry
+// itself for health transitions and evictions.
+const (
+	ActorHTTP   = "http"
+	ActorGRPC   = "grpc"
+	ActorSystem = "system"
+)
+
+// AuditEntry records one mutation of the registry. Before is empty for
+// registrations and After for deregistrations and evictions.
+type AuditEntry struct {
+	ID       uint64      `json:"id"`
+	Time     time.Time   `json:"time"`
+	Action   AuditAction `json:"action"`
+	Service  string      `json:"service"`
+	Actor    string      `json:"actor"`
+	TokenID  string      `json:"token_id,omitempty"`
+	SourceIP string      `json:"source_ip,omitempty"`
+	Before   *Service    `json:"before,omitempty"`
+	After    *Service    `json:"after,omitempty"`
+}
+
+// AuditQuery filters AuditLog.Query. Zero fields match every entry; Since is
+// inclusive and Until exclusive.
+type AuditQuery struct {
+	Since   time.Time
+	Until   time.Time
+	Service string
+	Action  AuditAction
+}
+
+func (q AuditQuery) match(entry AuditEntry) bool {
+	return (q.Since.IsZero() || !entry.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.Time.Before(q.Until)) &&
+		(q.Service == "" || entry.Service == q.Service) &&
+		(q.Action == "" || entry.Action == q.Action)
+}
+
+// AuditLog is the append-only trail of registry mutations. The latest entries
+// are kept in memory for queries; with AuditConfig.Path every entry is also
+// appended to a JSON lines file.
+type AuditLog struct {
+	mu       sync.Mutex
+	logger   *zap.Logger
+	clock    clock.Clock
+	capacity int
+	// entries is a ring buffer of up to capacity entries; once full, head is
+	// the index of the oldest one.
+	entries []AuditEntry
+	head    int
+	id      uint64
+	file    *os.File
+}
+
+func NewAuditLog(cfg AuditConfig, clk clock.Clock, logger *zap.Logger) (*AuditLog, error) {
+	capacity := cfg.Capacity
+	if capacity == 0 {
+		capacity = DefaultAuditCapacity
+	}
+
+	audit := &AuditLog{
+		logger:   logger,
+		clock:    clk,
+		capacity: capacity,
+	}
+
+	if cfg.Path != "" {
+		file, err := os.OpenFile(cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		audit.file = file
+	}
+
+	return audit, nil
+}
+
+// Record stamps entry with an id and the current time and appends it.
+func (a *AuditLog) Record(entry AuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.id++
+	entry.ID = a.id
+	entry.Time = a.clock.Now().UTC()
+
+	if len(a.entries) < a.capacity {
+		a.entries = append(a.entries, entry)
+	} else {
+		a.entries[a.head] = entry
+		a.head = (a.head + 1) % a.capacity
+	}
+
+	if a.file != nil {
+		if err := json.NewEncoder(a.file).Encode(entry); err != nil {
+			a.logger.Error("goreg->[server]: audit write error: " + err.Error())
+		}
+	}
+}
+
+// Query returns the entries in memory matching q, oldest first.
+func (a *AuditLog) Query(q AuditQuery) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries := make([]AuditEntry, 0)
+	for i := range a.entries {
+		entry := a.entries[(a.head+i)%len(a.entries)]
+		if q.match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// WriteJSONLines writes entries one JSON document per line.
+func WriteJSONLines(w io.Writer, entries []AuditEntry) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// AuditV1Handler queries the audit log (GET, admin on "*"). since and until
+// are RFC 3339 times; format=jsonl, or an Accept of application/x-ndjson,
+// exports the entries as JSON lines.
+func (g *Server) AuditV1Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, "GET")
+		return
+	}
+
+	if !g.allowedV1(w, r, "*", CapabilityAdmin) {
+		return
+	}
+
+	q, err := parseAuditQuery(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+
+	entries := []AuditEntry{}
+	if g.audit != nil {
+		entries = g.audit.Query(q)
+	}
+
+	if r.URL.Query().Get("format") == "jsonl" || strings.Contains(r.Header.Get("Accept"), jsonLinesContentType) {
+		w.Header().Set("Content-Type", jsonLinesContentType)
+		WriteJSONLines(w, entries)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+const jsonLinesContentType = "application/x-ndjson"
+
+func parseAuditQuery(r *http.Request) (AuditQuery, error) {
+	query := r.URL.Query()
+	q := AuditQuery{
+		Service: query.Get("service"),
+		Action:  AuditAction(query.Get("action")),
+	}
+
+	var err error
+	if raw := query.Get("since"); raw != "" {
+		if q.Since, err = time.Parse(time.RFC3339, raw); err != nil {
+			return AuditQuery{}, errors.New("since must be an RFC 3339 time")
+		}
+	}
+	if raw := query.Get("until"); raw != "" {
+		if q.Until, err = time.Parse(time.RFC3339, raw); err != nil {
+			return AuditQuery{}, errors.New("until must be an RFC 3339 time")
+		}
+	}
+
+	return q, nil
+}
+
+// caller identifies who made a change, see AuditEntry.
+type caller struct {
+	actor    string
+	tokenID  string
+	sourceIP string
+}
+
+var systemCaller = caller{actor: ActorSystem}
+
+func (g *Server) httpCaller(r *http.Request) caller {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return caller{actor: ActorHTTP, tokenID: g.tokenID(RequestToken(r)), sourceIP: host}
+}
+
+func (g *Server) rpcCaller(ctx context.Context) caller {
+	c := caller{actor: ActorGRPC, tokenID: g.tokenID(rpcToken(ctx))}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			c.sourceIP = host
+		}
+	}
+	return c
+}
+
+// tokenID resolves the ID of an ACL token secret, never the secret itself.
+func (g *Server) tokenID(secret string) string {
+	if g.acl == nil || secret == "" {
+		return ""
+	}
+	if token, ok := g.acl.Token(secret); ok {
+		return token.ID
+	}
+	return ""
+}
+
+// record adds a mutation made by c to the audit log and to the history of
+// the instance.
+func (g *Server) record(c caller, action AuditAction, before, after *Service) {
+	if g.history != nil {
+		switch action {
+		case AuditRegister:
+			g.history.Registered(after.Name, after.Hash)
+		case AuditDeregister:
+			g.history.Removed(before.Name, before.Hash, StateDeregistered)
+		case AuditEvict:
+			g.history.Removed(before.Name, before.Hash, StateEvicted)
+		}
+	}
+
+	if g.audit == nil {
+		return
+	}
+
+	name := ""
+	switch {
+	case after != nil:
+		name = after.Name
+	case before != nil:
+		name = before.Name
+	}
+
+	g.audit.Record(AuditEntry{
+		Action:   action,
+		Service:  name,
+		Actor:    c.actor,
+		TokenID:  c.tokenID,
+		SourceIP: c.sourceIP,
+		Before:   before,
+		After:    after,
+	})
+}
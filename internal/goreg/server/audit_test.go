@@ -0,0 +1,251 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+	"go.uber.org/zap"
+)
+
+func TestAuditLog_Query(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	manual := clock.NewManual(start)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	audit, err := NewAuditLog(AuditConfig{Path: path, Capacity: 3}, manual, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []AuditEntry{
+		{Action: AuditRegister, Service: "orders"},
+		{Action: AuditRegister, Service: "billing"},
+		{Action: AuditHealth, Service: "orders"},
+		{Action: AuditDeregister, Service: "orders"},
+	} {
+		audit.Record(entry)
+		manual.Advance(time.Minute)
+	}
+
+	tests := []struct {
+		name  string
+		query AuditQuery
+		ids   []uint64
+	}{
+		{"all kept", AuditQuery{}, []uint64{2, 3, 4}},
+		{"service", AuditQuery{Service: "orders"}, []uint64{3, 4}},
+		{"action", AuditQuery{Action: AuditRegister}, []uint64{2}},
+		{"since", AuditQuery{Since: start.Add(2 * time.Minute)}, []uint64{3, 4}},
+		{"until", AuditQuery{Until: start.Add(2 * time.Minute)}, []uint64{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := audit.Query(tt.query)
+			if len(entries) != len(tt.ids) {
+				t.Fatalf("unexpected entries: %+v", entries)
+			}
+			for i, entry := range entries {
+				if entry.ID != tt.ids[i] {
+					t.Errorf("entry %d has id %d, want %d", i, entry.ID, tt.ids[i])
+				}
+			}
+		})
+	}
+
+	if err := audit.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lines := 0
+	for scanner := bufio.NewScanner(f); scanner.Scan(); lines++ {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %d is not an entry: %v", lines+1, err)
+		}
+	}
+	if lines != 4 {
+		t.Errorf("expected every entry in the file, got %d lines", lines)
+	}
+}
+
+func TestAuditLog_Wraps(t *testing.T) {
+	audit, err := NewAuditLog(AuditConfig{Capacity: 3}, clock.NewManual(time.Now()), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 8; i++ {
+		audit.Record(AuditEntry{Action: AuditRegister, Service: "orders"})
+	}
+
+	entries := audit.Query(AuditQuery{})
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	for i, entry := range entries {
+		if want := uint64(6 + i); entry.ID != want {
+			t.Errorf("entry %d has id %d, want %d", i, entry.ID, want)
+		}
+	}
+}
+
+func TestAuditV1Handler(t *testing.T) {
+	server, err := NewServer(ServerConfig{Port: 8080, ACL: ACLConfig{
+		Enabled:       true,
+		DefaultPolicy: ACLPolicyDeny,
+		Policies: []ACLPolicy{{
+			Name:  "admin",
+			Rules: []ACLRule{{Service: "*", Capabilities: []Capability{CapabilityAdmin}}},
+		}},
+		Tokens: []ACLToken{{ID: "root", Secret: "root-secret", Policies: []string{"admin"}}},
+	}}, WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.Handler()
+
+	serve := func(method, target string, body any) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if body != nil {
+			data, _ := json.Marshal(body)
+			req = httptest.NewRequest(method, target, bytes.NewReader(data))
+		}
+		req.Header.Set("Authorization", "Bearer root-secret")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	serve(http.MethodPost, "/v1/services", Service{Name: "orders", Callback: "http://orders:8080/callback"})
+	serve(http.MethodPut, "/v1/services/orders", Service{Callback: "http://orders:9090/callback"})
+	serve(http.MethodDelete, "/v1/services/orders", nil)
+
+	rr := serve(http.MethodGet, "/v1/audit?service=orders", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rr.Code)
+	}
+
+	var entries []AuditEntry
+	if err := json.NewDecoder(rr.Body).Decode(&entries); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AuditAction{AuditRegister, AuditUpdate, AuditDeregister}
+	if len(entries) != len(want) {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	for i, entry := range entries {
+		if entry.Action != want[i] || entry.Actor != ActorHTTP || entry.TokenID != "root" || entry.SourceIP != "192.0.2.1" {
+			t.Errorf("unexpected entry %d: %+v", i, entry)
+		}
+	}
+	if entries[1].Before.Callback != "http://orders:8080/callback" || entries[1].After.Callback != "http://orders:9090/callback" {
+		t.Errorf("update entry lacks before/after: %+v", entries[1])
+	}
+	if entries[2].Before == nil || entries[2].After != nil {
+		t.Errorf("deregister entry should only carry before: %+v", entries[2])
+	}
+
+	rr = serve(http.MethodGet, "/v1/audit?format=jsonl&action=register", nil)
+	if rr.Header().Get("Content-Type") != jsonLinesContentType {
+		t.Errorf("unexpected content type %q", rr.Header().Get("Content-Type"))
+	}
+	var entry AuditEntry
+	if err := json.NewDecoder(rr.Body).Decode(&entry); err != nil || entry.Action != AuditRegister {
+		t.Errorf("unexpected json lines export: %v %+v", err, entry)
+	}
+
+	if rr := serve(http.MethodGet, "/v1/audit?since=yesterday", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid since returned %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/audit", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("anonymous audit query returned %d", rr.Code)
+	}
+}
+
+func TestServer_EvictCritical(t *testing.T) {
+	manual := clock.NewManual(time.Now())
+	server, err := NewServer(ServerConfig{Port: 8080, EvictAfter: time.Minute},
+		WithLogger(zap.NewNop()), WithHTTPClient(failingHTTPClient{}), WithClock(manual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.store.Set("orders", "http://orders:8080/callback")
+
+	check := func() {
+		orders, err := server.store.Get("orders")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.checkServiceAvailability(*orders)
+	}
+
+	check()
+	manual.Advance(30 * time.Second)
+	check()
+	if _, err := server.store.Get("orders"); err != nil {
+		t.Fatal("service was evicted before evict_after")
+	}
+
+	manual.Advance(30 * time.Second)
+	check()
+	if _, err := server.store.Get("orders"); err == nil {
+		t.Fatal("service was not evicted after evict_after")
+	}
+
+	entries := server.audit.Query(AuditQuery{Service: "orders"})
+	if len(entries) != 2 || entries[0].Action != AuditHealth || entries[1].Action != AuditEvict || entries[1].Actor != ActorSystem {
+		t.Errorf("unexpected audit entries: %+v", entries)
+	}
+	if entries[0].Before.Status != StatusPassing || entries[0].After.Status != StatusCritical {
+		t.Errorf("health entry lacks the transition: %+v", entries[0])
+	}
+}
+
+func TestServer_EvictCriticalReregistered(t *testing.T) {
+	manual := clock.NewManual(time.Now())
+	server, err := NewServer(ServerConfig{Port: 8080, EvictAfter: time.Minute},
+		WithLogger(zap.NewNop()), WithHTTPClient(failingHTTPClient{}), WithClock(manual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.store.Set("orders", "http://orders:8080/callback")
+	critical, _ := server.store.Get("orders")
+
+	server.evictCritical(*critical, StatusCritical)
+	manual.Advance(time.Minute)
+
+	// The instance re-registers between the probe and the eviction.
+	server.store.Delete("orders")
+	server.store.Set("orders", "http://orders:8080/callback")
+	newer, _ := server.store.Get("orders")
+
+	server.evictCritical(*critical, StatusCritical)
+
+	current, err := server.store.Get("orders")
+	if err != nil || current.Hash != newer.Hash {
+		t.Fatalf("expected newer registration to survive eviction, got %v %v", current, err)
+	}
+
+	if entries := server.audit.Query(AuditQuery{Service: "orders", Action: AuditEvict}); len(entries) != 0 {
+		t.Errorf("unexpected evict entries: %+v", entries)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// MaxBatchSize is the largest number of operations in one batch.
+const MaxBatchSize = 128
+
+type BatchRequest struct {
+	Operations []BatchOp `json:"operations"`
+}
+
+// BatchResponse reports whether a batch was applied and the outcome of each
+// operation, in request order. Index is the store index of an applied batch.
+type BatchResponse struct {
+	Applied bool              `json:"applied"`
+	Index   uint64            `json:"index,omitempty"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// BatchItemResult carries Service on success and a problem Code and Detail
+// otherwise. Operations of a rejected batch that did not fail themselves have
+// CodeBatchAborted.
+type BatchItemResult struct {
+	Verb    BatchVerb `json:"verb"`
+	Name    string    `json:"name"`
+	Service *Service  `json:"service,omitempty"`
+	Code    string    `json:"code,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+var batchAborted = &batchFailure{http.StatusConflict, CodeBatchAborted, "rolled back with the batch"}
+
+// batchFailure is why one operation failed, with the status the whole batch
+// is rejected with when it is the first failure.
+type batchFailure struct {
+	status int
+	code   string
+	detail string
+}
+
+// BatchV1Handler applies a batch of register, deregister and renew
+// operations atomically (POST). An applied batch answers 200; a rejected one
+// answers with the status of its first failed operation, and the results
+// tell every failure apart.
+func (g *Server) BatchV1Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, "POST")
+		return
+	}
+
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "body is not a valid batch: "+err.Error())
+		return
+	}
+
+	if len(req.Operations) == 0 || len(req.Operations) > MaxBatchSize {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "a batch takes 1 to "+strconv.Itoa(MaxBatchSize)+" operations")
+		return
+	}
+
+	c := g.httpCaller(r)
+	failures := make([]*batchFailure, len(req.Operations))
+	rejected := false
+	for i, op := range req.Operations {
+		if op.Verb == BatchRegister {
+			op.Service = advertised(op.Service, c)
+			req.Operations[i] = op
+		}
+		failures[i] = g.checkBatchOp(r, op)
+		rejected = rejected || failures[i] != nil
+	}
+
+	var (
+		results []BatchResult
+		index   uint64
+	)
+	if !rejected {
+		var err error
+		results, index, err = g.store.Apply(req.Operations)
+		if err != nil {
+			rejected = true
+			for i, result := range results {
+				failures[i] = storeFailure(req.Operations[i], result.Err)
+			}
+		}
+	}
+
+	if rejected {
+		writeBatchRejected(w, req.Operations, failures)
+		return
+	}
+
+	response := BatchResponse{Applied: true, Index: index, Results: make([]BatchItemResult, len(results))}
+	for i, result := range results {
+		op := req.Operations[i]
+		switch op.Verb {
+		case BatchRegister:
+			g.metrics.registered()
+			g.record(c, AuditRegister, nil, result.Service)
+		case BatchDeregister:
+			g.metrics.deregistered()
+			g.record(c, AuditDeregister, result.Service, nil)
+		}
+		response.Results[i] = BatchItemResult{Verb: op.Verb, Name: op.Service.Name, Service: result.Service}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// checkBatchOp validates and authorizes one operation before the batch is
+// applied.
+func (g *Server) checkBatchOp(r *http.Request, op BatchOp) *batchFailure {
+	name := op.Service.Name
+
+	var capability Capability
+	switch op.Verb {
+	case BatchRegister:
+		if err := validateService(op.Service); err != nil {
+			return &batchFailure{http.StatusBadRequest, CodeValidationFailed, err.Error()}
+		}
+		capability = CapabilityRegister
+	case BatchRenew:
+		if name == "" || op.Service.Hash == "" {
+			return &batchFailure{http.StatusBadRequest, CodeValidationFailed, "renew requires the service name and hash"}
+		}
+		capability = CapabilityRegister
+	case BatchDeregister:
+		if name == "" {
+			return &batchFailure{http.StatusBadRequest, CodeValidationFailed, "deregister requires the service name"}
+		}
+		capability = CapabilityDeregister
+	default:
+		return &batchFailure{http.StatusBadRequest, CodeInvalidBody, "unknown verb " + strconv.Quote(string(op.Verb))}
+	}
+
+	if !g.allowed(r, name, capability) {
+		return &batchFailure{http.StatusForbidden, CodePermissionDenied, "token lacks " + string(capability) + " on " + name}
+	}
+	return nil
+}
+
+func storeFailure(op BatchOp, err error) *batchFailure {
+	name := op.Service.Name
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrBatchAborted):
+		return batchAborted
+	case errors.Is(err, ErrServiceExists):
+		return &batchFailure{http.StatusConflict, CodeServiceExists, "service " + name + " is already registered"}
+	case errors.Is(err, ErrServiceNotFound) && op.Service.Hash != "":
+		return &batchFailure{http.StatusNotFound, CodeInstanceNotFound, "service " + name + " has no instance " + op.Service.Hash}
+	case errors.Is(err, ErrServiceNotFound):
+		return &batchFailure{http.StatusNotFound, CodeServiceNotFound, "service " + name + " is not registered"}
+	default:
+		return &batchFailure{http.StatusInternalServerError, CodeInternal, err.Error()}
+	}
+}
+
+func writeBatchRejected(w http.ResponseWriter, ops []BatchOp, failures []*batchFailure) {
+	var first *batchFailure
+	response := BatchResponse{Results: make([]BatchItemResult, len(ops))}
+	for i, op := range ops {
+		failure := failures[i]
+		if failure == nil {
+			failure = batchAborted
+		}
+		if first == nil && failure != batchAborted {
+			first = failure
+		}
+		response.Results[i] = BatchItemResult{Verb: op.Verb, Name: op.Service.Name, Code: failure.code, Detail: failure.detail}
+	}
+
+	if first == nil {
+		first = batchAborted
+	}
+	writeJSON(w, first.status, response)
+}
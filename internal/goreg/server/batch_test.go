@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestBatchV1Handler(t *testing.T) {
+	server := setupTestServer()
+	handler := server.Handler()
+
+	serveV1(t, handler, http.MethodPost, "/v1/services", Service{Name: "orders", Callback: "http://orders:8080/callback"})
+	orders, _ := server.store.Get("orders")
+
+	register := func(name string) BatchOp {
+		return BatchOp{Verb: BatchRegister, Service: Service{Name: name, Callback: "http://" + name + ":8080/callback"}}
+	}
+
+	tests := []struct {
+		name    string
+		ops     []BatchOp
+		code    int
+		applied bool
+		codes   []string
+	}{
+		{
+			name:    "applied",
+			ops:     []BatchOp{register("billing"), {Verb: BatchRenew, Service: Service{Name: "orders", Hash: orders.Hash}}},
+			code:    http.StatusOK,
+			applied: true,
+			codes:   []string{"", ""},
+		},
+		{
+			name:  "conflict rolls back",
+			ops:   []BatchOp{register("users"), register("billing")},
+			code:  http.StatusConflict,
+			codes: []string{CodeBatchAborted, CodeServiceExists},
+		},
+		{
+			name:  "stale renew",
+			ops:   []BatchOp{{Verb: BatchDeregister, Service: Service{Name: "billing"}}, {Verb: BatchRenew, Service: Service{Name: "orders", Hash: "stale"}}},
+			code:  http.StatusNotFound,
+			codes: []string{CodeBatchAborted, CodeInstanceNotFound},
+		},
+		{
+			name:  "invalid operation",
+			ops:   []BatchOp{register("users"), {Verb: BatchRegister, Service: Service{Name: "-bad-", Callback: "http://bad"}}, {Verb: "purge"}},
+			code:  http.StatusBadRequest,
+			codes: []string{CodeBatchAborted, CodeValidationFailed, CodeInvalidBody},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveV1(t, handler, http.MethodPost, "/v1/batch", BatchRequest{Operations: tt.ops})
+			if rr.Code != tt.code {
+				t.Fatalf("expected status %d, got %d: %s", tt.code, rr.Code, rr.Body)
+			}
+
+			var response BatchResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Applied != tt.applied || len(response.Results) != len(tt.codes) {
+				t.Fatalf("unexpected response: %+v", response)
+			}
+			for i, result := range response.Results {
+				if result.Code != tt.codes[i] {
+					t.Errorf("operation %d: got code %q want %q", i, result.Code, tt.codes[i])
+				}
+				if tt.applied && result.Service == nil {
+					t.Errorf("operation %d lacks the service", i)
+				}
+			}
+		})
+	}
+
+	if _, err := server.store.Get("users"); err == nil {
+		t.Error("users was registered by a rejected batch")
+	}
+	if _, err := server.store.Get("billing"); err != nil {
+		t.Error("billing was deregistered by a rejected batch")
+	}
+
+	if rr := serveV1(t, handler, http.MethodPost, "/v1/batch", BatchRequest{}); rr.Code != http.StatusBadRequest {
+		t.Errorf("empty batch returned %d", rr.Code)
+	}
+}
+
+func TestBatchV1Handler_PermissionDenied(t *testing.T) {
+	server := setupTestServer()
+	acl, err := NewACL(ACLConfig{Enabled: true, DefaultPolicy: ACLPolicyDeny})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.acl = acl
+
+	rr := serveV1(t, server.Handler(), http.MethodPost, "/v1/batch", BatchRequest{Operations: []BatchOp{
+		{Verb: BatchDeregister, Service: Service{Name: "orders"}},
+	}})
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status %d", rr.Code)
+	}
+
+	var response BatchResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	if len(response.Results) != 1 || response.Results[0].Code != CodePermissionDenied {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reads report the store index in IndexHeader. Passing it back as ?index=N
+// turns the read into a blocking query that only returns once the store index
+// has moved past N, or after ?wait (DefaultQueryWait, at most MaxQueryWait).
+const (
+	IndexHeader      = "X-Goreg-Index"
+	DefaultQueryWait = 5 * time.Minute
+	MaxQueryWait     = 10 * time.Minute
+)
+
+// blockingQuery waits for the store to change when r is a blocking query and
+// sets IndexHeader. It returns the store index the response is built from.
+func (g *Server) blockingQuery(w http.ResponseWriter, r *http.Request) (uint64, error) {
+	index, wait, err := parseBlockingQuery(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if index != 0 {
+		g.waitIndex(r.Context(), index, wait)
+	}
+
+	current := g.store.Index()
+	w.Header().Set(IndexHeader, strconv.FormatUint(current, 10))
+	return current, nil
+}
+
+func parseBlockingQuery(r *http.Request) (index uint64, wait time.Duration, err error) {
+	query := r.URL.Query()
+
+	if raw := query.Get("index"); raw != "" {
+		if index, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			return 0, 0, errors.New("index must be a store index")
+		}
+	}
+
+	wait = DefaultQueryWait
+	if raw := query.Get("wait"); raw != "" {
+		if wait, err = time.ParseDuration(raw); err != nil || wait <= 0 {
+			return 0, 0, errors.New("wait must be a positive duration")
+		}
+		wait = min(wait, MaxQueryWait)
+	}
+
+	return index, wait, nil
+}
+
+// waitIndex blocks until the store index is past index, wait elapses, the
+// request is cancelled or the server shuts down.
+func (g *Server) waitIndex(ctx context.Context, index uint64, wait time.Duration) {
+	timeout := g.clock.After(wait)
+	for {
+		changed := g.store.Changed()
+		if g.store.Index() > index {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout:
+			return
+		case <-ctx.Done():
+			return
+		case <-g.closeCh:
+			return
+		}
+	}
+}
+
+// setETag tags the response with the modify index of service.
+func setETag(w http.ResponseWriter, service *Service) {
+	setIndexETag(w, service.ModifyIndex)
+}
+
+func setIndexETag(w http.ResponseWriter, index uint64) {
+	w.Header().Set("ETag", `"`+strconv.FormatUint(index, 10)+`"`)
+}
+
+// setListETag tags a list filtered by the ACL with the store index and the ACL
+// generation, so a policy change invalidates it as well.
+func (g *Server) setListETag(w http.ResponseWriter, index uint64) {
+	if g.acl == nil {
+		setIndexETag(w, index)
+		return
+	}
+	w.Header().Set("ETag", `"`+strconv.FormatUint(index, 10)+"-"+strconv.FormatUint(g.acl.Generation(), 10)+`"`)
+}
+
+// notModified answers 304 Not Modified when If-None-Match holds the ETag
+// already set on w.
+func notModified(w http.ResponseWriter, r *http.Request) bool {
+	header := r.Header.Get("If-None-Match")
+	etag := w.Header().Get("ETag")
+	if header == "" || etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+)
+
+func serveAsync(handler http.Handler, req *http.Request) <-chan *httptest.ResponseRecorder {
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		done <- rr
+	}()
+	return done
+}
+
+func expectBlocked(t *testing.T, done <-chan *httptest.ResponseRecorder) {
+	t.Helper()
+
+	select {
+	case rr := <-done:
+		t.Fatalf("blocking query returned early with %d", rr.Code)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func expectDone(t *testing.T, done <-chan *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	t.Helper()
+
+	select {
+	case rr := <-done:
+		return rr
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocking query did not return")
+		return nil
+	}
+}
+
+func TestBlockingQuery_WakesOnChange(t *testing.T) {
+	server := setupTestServer()
+	server.clock = clock.NewManual(time.Now())
+	server.store.Set("orders", "http://orders:8080/callback")
+	handler := server.Handler()
+
+	targets := []string{"/v1/services?index=", "/v1/services/orders?index=", "/get?name=orders&index=", "/getall?index="}
+	for i, target := range targets {
+		t.Run(target, func(t *testing.T) {
+			target += strconv.FormatUint(server.store.Index(), 10)
+			done := serveAsync(handler, httptest.NewRequest(http.MethodGet, target, nil))
+			expectBlocked(t, done)
+
+			server.store.SetStatus("orders", []HealthStatus{StatusCritical, StatusPassing}[i%2])
+
+			rr := expectDone(t, done)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("unexpected status %d", rr.Code)
+			}
+			if got, want := rr.Header().Get(IndexHeader), server.store.Index(); got != strconv.FormatUint(want, 10) {
+				t.Errorf("unexpected index header %q, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestBlockingQuery_Timeout(t *testing.T) {
+	server := setupTestServer()
+	manual := clock.NewManual(time.Now())
+	server.clock = manual
+	server.store.Set("orders", "http://orders:8080/callback")
+
+	done := serveAsync(server.Handler(), httptest.NewRequest(http.MethodGet, "/v1/services?index=1&wait=30s", nil))
+	expectBlocked(t, done)
+
+	manual.Advance(30 * time.Second)
+	if rr := expectDone(t, done); rr.Code != http.StatusOK || rr.Header().Get(IndexHeader) != "1" {
+		t.Errorf("unexpected response %d with index %q", rr.Code, rr.Header().Get(IndexHeader))
+	}
+}
+
+func TestBlockingQuery_Shutdown(t *testing.T) {
+	server := setupTestServer()
+	server.clock = clock.NewManual(time.Now())
+	server.store.Set("orders", "http://orders:8080/callback")
+
+	done := serveAsync(server.Handler(), httptest.NewRequest(http.MethodGet, "/get?name=orders&index=1", nil))
+	expectBlocked(t, done)
+
+	server.Shutdown(context.Background())
+	expectDone(t, done)
+}
+
+func TestBlockingQuery_CurrentIndex(t *testing.T) {
+	server := setupTestServer()
+	server.clock = clock.NewManual(time.Now())
+	server.store.Set("orders", "http://orders:8080/callback")
+	handler := server.Handler()
+
+	tests := []struct {
+		name   string
+		target string
+		code   int
+	}{
+		{"stale index", "/v1/services?index=0", http.StatusOK},
+		{"past index", "/v1/services/orders?wait=1s", http.StatusOK},
+		{"invalid index", "/v1/services?index=abc", http.StatusBadRequest},
+		{"invalid wait", "/v1/services/orders?index=1&wait=-1s", http.StatusBadRequest},
+		{"legacy invalid wait", "/getall?index=1&wait=soon", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := expectDone(t, serveAsync(handler, httptest.NewRequest(http.MethodGet, tt.target, nil)))
+			if rr.Code != tt.code {
+				t.Errorf("unexpected status: got %d want %d", rr.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	server := setupTestServer()
+	server.store.Set("orders", "http://orders:8080/callback")
+	handler := server.Handler()
+
+	tests := []struct {
+		name        string
+		target      string
+		ifNoneMatch string
+		code        int
+	}{
+		{"service current", "/v1/services/orders", `"1"`, http.StatusNotModified},
+		{"service stale", "/v1/services/orders", `"0"`, http.StatusOK},
+		{"instances current", "/v1/services/orders/instances", `W/"1"`, http.StatusNotModified},
+		{"list current", "/v1/services", `"0", "1"`, http.StatusNotModified},
+		{"list any", "/v1/services", `*`, http.StatusNotModified},
+		{"legacy current", "/get?name=orders", `"1"`, http.StatusNotModified},
+		{"legacy list stale", "/getall", `"7"`, http.StatusOK},
+		{"no header", "/getall", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.code {
+				t.Fatalf("unexpected status: got %d want %d", rr.Code, tt.code)
+			}
+			if rr.Code == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("304 carries a body: %q", rr.Body)
+			}
+		})
+	}
+}
+
+func TestConditionalGet_ACLChange(t *testing.T) {
+	server := setupTestServer()
+	server.acl, _ = NewACL(testACLConfig())
+	server.store.Set("orders", "http://orders:8080/callback")
+	handler := server.Handler()
+
+	for _, target := range []string{"/v1/services", "/getall"} {
+		t.Run(target, func(t *testing.T) {
+			list := func(ifNoneMatch string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, target, nil)
+				req.Header.Set(tokenHeader, "payments-secret")
+				if ifNoneMatch != "" {
+					req.Header.Set("If-None-Match", ifNoneMatch)
+				}
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				return rr
+			}
+
+			rr := list("")
+			etag := rr.Header().Get("ETag")
+			if rr.Code != http.StatusOK || etag == "" {
+				t.Fatalf("unexpected response: %d, etag %q", rr.Code, etag)
+			}
+
+			if rr := list(etag); rr.Code != http.StatusNotModified {
+				t.Fatalf("expected 304 for an unchanged list, got %d", rr.Code)
+			}
+
+			err := server.acl.SetPolicy(ACLPolicy{
+				Name:  "payments",
+				Rules: []ACLRule{{Service: "*", Capabilities: []Capability{CapabilityRead}}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr = list(etag)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected a policy change to invalidate the etag, got %d", rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), "orders") {
+				t.Errorf("expected newly readable service, got %s", rr.Body)
+			}
+		})
+	}
+}
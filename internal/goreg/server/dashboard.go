@@ -0,0 +1,34 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed ui
+var dashboardFS embed.FS
+
+var dashboardAssets = func() http.Handler {
+	sub, err := fs.Sub(dashboardFS, "ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/ui/", http.FileServerFS(sub))
+}()
+
+// DashboardHandler serves the web dashboard. The page only reads the /v1 API
+// with the token the operator enters, so listings and the deregister action
+// are gated by the same ACL as any other client.
+func (g *Server) DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if err := ValidateHttpMethod(r.Method, http.MethodGet); err != nil {
+		http.Error(w, err.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-cache")
+	dashboardAssets.ServeHTTP(w, r)
+}
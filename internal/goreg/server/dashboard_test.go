@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDashboardHandler(t *testing.T) {
+	server, err := NewServer(ServerConfig{Port: 8080, UI: UIConfig{Enabled: true}}, WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.Handler()
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		code        int
+		contentType string
+	}{
+		{"index", http.MethodGet, "/ui/", http.StatusOK, "text/html"},
+		{"script", http.MethodGet, "/ui/app.js", http.StatusOK, "text/javascript"},
+		{"style", http.MethodGet, "/ui/style.css", http.StatusOK, "text/css"},
+		{"missing asset", http.MethodGet, "/ui/missing.js", http.StatusNotFound, ""},
+		{"method", http.MethodPost, "/ui/", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rr.Code != tt.code {
+				t.Fatalf("expected status %d, got %d", tt.code, rr.Code)
+			}
+			if !strings.HasPrefix(rr.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("unexpected content type %q", rr.Header().Get("Content-Type"))
+			}
+			if tt.code == http.StatusOK && rr.Header().Get("X-Frame-Options") != "DENY" {
+				t.Error("expected the dashboard to refuse framing")
+			}
+		})
+	}
+}
+
+func TestDashboardHandler_Redirect(t *testing.T) {
+	server, err := NewServer(ServerConfig{Port: 8080, UI: UIConfig{Enabled: true}}, WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ui", nil))
+	if rr.Code/100 != 3 || rr.Header().Get("Location") != "/ui/" {
+		t.Errorf("expected a redirect to /ui/, got %d to %q", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestDashboardHandler_Disabled(t *testing.T) {
+	server, err := NewServer(ServerConfig{Port: 8080}, WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ui/", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("disabled dashboard returned %d", rr.Code)
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+const dnsDomain = "service.goreg."
+
+// DNSServer answers A, AAAA and SRV queries for <name>.service.goreg. from
+// the store. Only passing services are returned. Queries carry no ACL token,
+// so answers are not scoped by read rules; see DNSConfig.AllowUnscoped.
+type DNSServer struct {
+	logger *zap.Logger
+	store  *ServerStore
+	ttl    uint32
+	server *dns.Server
+
+	started atomic.Bool
+}
+
+func NewDNSServer(cfg DNSConfig, store *ServerStore, logger *zap.Logger) *DNSServer {
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = DefaultDNSTTL
+	}
+
+	addr := cfg.Address
+	if addr == "" {
+		addr = DefaultDNSAddress
+	}
+
+	d := &DNSServer{
+		logger: logger,
+		store:  store,
+		ttl:    uint32(ttl / time.Second),
+	}
+	d.server = &dns.Server{Addr: addr, Net: "udp", Handler: d}
+	return d
+}
+
+// Start serves on pc, or on the configured address when pc is nil, and
+// returns once the socket is ready or serving failed to start.
+func (d *DNSServer) Start(pc net.PacketConn) error {
+	if pc == nil {
+		var err error
+		if pc, err = net.ListenPacket("udp", d.server.Addr); err != nil {
+			return err
+		}
+	}
+
+	started := make(chan struct{})
+	errCh := make(chan error, 1)
+	d.server.PacketConn = pc
+	d.server.NotifyStartedFunc = func() { close(started) }
+
+	go func() {
+		errCh <- d.server.ActivateAndServe()
+	}()
+
+	select {
+	case <-started:
+	case err := <-errCh:
+		if err == nil {
+			err = errors.New("dns server stopped before it started")
+		}
+		return err
+	}
+
+	go func() {
+		if err := <-errCh; err != nil {
+			d.logger.Error("goreg->[server]: dns serve error: " + err.Error())
+		}
+	}()
+
+	d.started.Store(true)
+	d.logger.Info("goreg->[server]: dns was started at: " + pc.LocalAddr().String())
+	return nil
+}
+
+func (d *DNSServer) Shutdown(ctx context.Context) error {
+	if !d.started.CompareAndSwap(true, false) {
+		return nil
+	}
+	return d.server.ShutdownContext(ctx)
+}
+
+func (d *DNSServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if len(r.Question) == 1 {
+		d.answer(m, r.Question[0])
+	} else {
+		m.Rcode = dns.RcodeFormatError
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		d.logger.Warn("goreg->[server]: dns write error: " + err.Error())
+	}
+}
+
+func (d *DNSServer) answer(m *dns.Msg, q dns.Question) {
+	qname := strings.ToLower(q.Name)
+	name, ok := strings.CutSuffix(qname, "."+dnsDomain)
+	if !ok || name == "" || strings.Contains(name, ".") {
+		m.Rcode = dns.RcodeNameError
+		return
+	}
+
+	var services []*Service
+	for _, service := range d.store.GetAll() {
+		if strings.EqualFold(service.Name, name) && service.Status == StatusPassing {
+			services = append(services, service)
+		}
+	}
+
+	if len(services) == 0 {
+		m.Rcode = dns.RcodeNameError
+		return
+	}
+
+	rand.Shuffle(len(services), func(i, j int) {
+		services[i], services[j] = services[j], services[i]
+	})
+
+	cname := false
+	for _, service := range services {
+		target, err := service.Target()
+		if err != nil {
+			continue
+		}
+		host, portStr, _ := net.SplitHostPort(target)
+		port, _ := strconv.Atoi(portStr)
+
+		switch q.Qtype {
+		case dns.TypeA, dns.TypeAAAA:
+			if rr := d.addressRecord(qname, host, q.Qtype); rr != nil {
+				m.Answer = append(m.Answer, rr)
+			} else if net.ParseIP(host) == nil && !cname {
+				// A name may carry a single CNAME, so only the first
+				// hostname based instance is returned.
+				m.Answer = append(m.Answer, &dns.CNAME{Hdr: d.header(qname, dns.TypeCNAME), Target: dns.Fqdn(host)})
+				cname = true
+			}
+		case dns.TypeSRV:
+			srvTarget := dns.Fqdn(host)
+			if net.ParseIP(host) != nil {
+				srvTarget = qname
+			}
+			m.Answer = append(m.Answer, &dns.SRV{
+				Hdr:      d.header(qname, dns.TypeSRV),
+				Priority: 1,
+				Weight:   1,
+				Port:     uint16(port),
+				Target:   srvTarget,
+			})
+
+			for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+				if rr := d.addressRecord(srvTarget, host, qtype); rr != nil {
+					m.Extra = append(m.Extra, rr)
+				}
+			}
+		}
+	}
+}
+
+// addressRecord returns an A or AAAA record for host when it is an IP of the
+// requested family.
+func (d *DNSServer) addressRecord(name, host string, qtype uint16) dns.RR {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		if qtype != dns.TypeA {
+			return nil
+		}
+		return &dns.A{Hdr: d.header(name, dns.TypeA), A: ip4}
+	}
+
+	if qtype != dns.TypeAAAA {
+		return nil
+	}
+	return &dns.AAAA{Hdr: d.header(name, dns.TypeAAAA), AAAA: ip}
+}
+
+func (d *DNSServer) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: d.ttl}
+}
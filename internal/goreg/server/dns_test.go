@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+func setupDNSServer(t *testing.T) (*Server, string) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ServerConfig{Port: 8079, DNS: DNSConfig{Enabled: true, TTL: 5 * time.Second}}
+	server, err := NewServer(cfg, WithLogger(zap.NewNop()), WithListener(ln), WithDNSPacketConn(pc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+
+	return server, pc.LocalAddr().String()
+}
+
+func queryDNS(t *testing.T, addr, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+
+	in, _, err := new(dns.Client).Exchange(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+func TestDNSServer_StartError(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.Close()
+
+	d := NewDNSServer(DNSConfig{Enabled: true}, nil, zap.NewNop())
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Start(pc)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("expected error for a closed packet conn, got nil")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Start to return when serving fails")
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	server, addr := setupDNSServer(t)
+
+	server.store.SetService(Service{Name: "orders", Callback: "http://10.0.0.5:8080/callback", Port: 9000})
+	server.store.SetService(Service{Name: "billing", Callback: "http://[fd00::7]:8080/callback"})
+	server.store.SetService(Service{Name: "search", Callback: "http://search.internal:8080/callback"})
+	server.store.Set("broken", "http://10.0.0.9:8080/callback")
+	server.store.SetStatus("broken", StatusCritical)
+
+	tests := []struct {
+		name   string
+		qname  string
+		qtype  uint16
+		rcode  int
+		answer string
+	}{
+		{"A", "orders.service.goreg.", dns.TypeA, dns.RcodeSuccess, "orders.service.goreg.\t5\tIN\tA\t10.0.0.5"},
+		{"AAAA", "billing.service.goreg.", dns.TypeAAAA, dns.RcodeSuccess, "billing.service.goreg.\t5\tIN\tAAAA\tfd00::7"},
+		{"SRV", "orders.service.goreg.", dns.TypeSRV, dns.RcodeSuccess, "orders.service.goreg.\t5\tIN\tSRV\t1 1 9000 orders.service.goreg."},
+		{"SRV hostname", "search.service.goreg.", dns.TypeSRV, dns.RcodeSuccess, "search.service.goreg.\t5\tIN\tSRV\t1 1 8080 search.internal."},
+		{"CNAME", "search.service.goreg.", dns.TypeA, dns.RcodeSuccess, "search.service.goreg.\t5\tIN\tCNAME\tsearch.internal."},
+		{"case insensitive", "ORDERS.service.goreg.", dns.TypeA, dns.RcodeSuccess, "orders.service.goreg.\t5\tIN\tA\t10.0.0.5"},
+		{"critical", "broken.service.goreg.", dns.TypeA, dns.RcodeNameError, ""},
+		{"unknown", "missing.service.goreg.", dns.TypeA, dns.RcodeNameError, ""},
+		{"outside domain", "orders.example.com.", dns.TypeA, dns.RcodeNameError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := queryDNS(t, addr, tt.qname, tt.qtype)
+
+			if in.Rcode != tt.rcode {
+				t.Fatalf("unexpected rcode: got %s want %s", dns.RcodeToString[in.Rcode], dns.RcodeToString[tt.rcode])
+			}
+
+			if tt.answer == "" {
+				if len(in.Answer) != 0 {
+					t.Errorf("expected no answer, got %v", in.Answer)
+				}
+				return
+			}
+
+			if len(in.Answer) != 1 || in.Answer[0].String() != tt.answer {
+				t.Errorf("unexpected answer: got %v want %s", in.Answer, tt.answer)
+			}
+		})
+	}
+}
+
+func TestDNSServer_SRVAdditional(t *testing.T) {
+	server, addr := setupDNSServer(t)
+	server.store.SetService(Service{Name: "orders", Callback: "http://10.0.0.5:8080/callback"})
+
+	in := queryDNS(t, addr, "orders.service.goreg.", dns.TypeSRV)
+	if len(in.Extra) != 1 {
+		t.Fatalf("expected one additional record, got %v", in.Extra)
+	}
+	if a, ok := in.Extra[0].(*dns.A); !ok || a.A.String() != "10.0.0.5" {
+		t.Errorf("unexpected additional record: %v", in.Extra[0])
+	}
+}
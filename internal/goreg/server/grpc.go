@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Danis0n/goreg/internal/goreg/registrypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcRegistry serves the registrypb.Registry API from the server store.
+type grpcRegistry struct {
+	registrypb.UnimplementedRegistryServer
+	server *Server
+}
+
+func (g *Server) newGRPCServer() *grpc.Server {
+	var opts []grpc.ServerOption
+	if g.tls != nil && g.tls.Certificate() != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(g.tls.ServerTLSConfig())))
+	}
+
+	srv := grpc.NewServer(opts...)
+	registrypb.RegisterRegistryServer(srv, &grpcRegistry{server: g})
+	return srv
+}
+
+func (r *grpcRegistry) Register(ctx context.Context, req *registrypb.RegisterRequest) (*registrypb.RegisterResponse, error) {
+	svc := Service{
+		Name:     req.GetName(),
+		Callback: req.GetCallback(),
+		Address:  req.GetAddress(),
+		Port:     int(req.GetPort()),
+		Tags:     req.GetTags(),
+		Metadata: req.GetMetadata(),
+	}
+	svc = advertised(svc, r.server.rpcCaller(ctx))
+
+	if err := validateService(svc); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := r.server.authorizeRPC(ctx, svc.Name, CapabilityRegister); err != nil {
+		return nil, err
+	}
+
+	if err := r.server.store.SetService(svc); err != nil {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	r.server.metrics.registered()
+
+	service, err := r.server.store.Get(svc.Name)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	r.server.record(r.server.rpcCaller(ctx), AuditRegister, nil, service)
+
+	return &registrypb.RegisterResponse{Name: service.Name, Hash: service.Hash}, nil
+}
+
+func (r *grpcRegistry) Deregister(ctx context.Context, req *registrypb.DeregisterRequest) (*registrypb.DeregisterResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	if err := r.server.authorizeRPC(ctx, req.GetName(), CapabilityDeregister); err != nil {
+		return nil, err
+	}
+
+	removed, err := r.server.store.RemoveInstance(req.GetName(), req.GetHash())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	r.server.metrics.deregistered()
+	r.server.record(r.server.rpcCaller(ctx), AuditDeregister, removed, nil)
+
+	return &registrypb.DeregisterResponse{}, nil
+}
+
+func (r *grpcRegistry) Renew(ctx context.Context, req *registrypb.RenewRequest) (*registrypb.RenewResponse, error) {
+	if req.GetName() == "" || req.GetHash() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name and hash are required")
+	}
+
+	if err := r.server.authorizeRPC(ctx, req.GetName(), CapabilityRegister); err != nil {
+		return nil, err
+	}
+
+	service, err := r.server.store.Get(req.GetName())
+	if err != nil || service.Hash != req.GetHash() {
+		return nil, status.Error(codes.NotFound, "registration not found")
+	}
+
+	return &registrypb.RenewResponse{Service: toProtoService(service)}, nil
+}
+
+func (r *grpcRegistry) Get(ctx context.Context, req *registrypb.GetRequest) (*registrypb.Service, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	if err := r.server.authorizeRPC(ctx, req.GetName(), CapabilityRead); err != nil {
+		return nil, err
+	}
+
+	service, err := r.server.store.Get(req.GetName())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return toProtoService(service), nil
+}
+
+func (r *grpcRegistry) List(ctx context.Context, req *registrypb.ListRequest) (*registrypb.ListResponse, error) {
+	return &registrypb.ListResponse{Services: r.list(ctx)}, nil
+}
+
+func (r *grpcRegistry) Watch(req *registrypb.WatchRequest, stream grpc.ServerStreamingServer[registrypb.WatchResponse]) error {
+	ctx := stream.Context()
+
+	for {
+		// Take the channel before the snapshot so no change is missed.
+		changed := r.server.store.Changed()
+
+		if err := stream.Send(&registrypb.WatchResponse{Services: r.list(ctx)}); err != nil {
+			return err
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.server.closeCh:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+// list returns the services readable with the caller token, sorted by name.
+func (r *grpcRegistry) list(ctx context.Context) []*registrypb.Service {
+	services := r.server.store.GetAll()
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	token := rpcToken(ctx)
+	out := make([]*registrypb.Service, 0, len(services))
+	for _, service := range services {
+		if r.server.acl == nil || r.server.acl.Authorize(token, service.Name, CapabilityRead) {
+			out = append(out, toProtoService(service))
+		}
+	}
+	return out
+}
+
+func (g *Server) authorizeRPC(ctx context.Context, service string, capability Capability) error {
+	if g.acl == nil || g.acl.Authorize(rpcToken(ctx), service, capability) {
+		return nil
+	}
+
+	g.logger.Warn("goreg->[server]: permission denied: " + string(capability) + " on {" + service + "}")
+	return status.Error(codes.PermissionDenied, "permission denied")
+}
+
+// rpcToken reads the ACL token from the same headers RequestToken uses, sent
+// as gRPC metadata.
+func rpcToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if token := md.Get(strings.ToLower(tokenHeader)); len(token) > 0 && token[0] != "" {
+		return token[0]
+	}
+
+	if auth := md.Get("authorization"); len(auth) > 0 && strings.HasPrefix(auth[0], "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth[0], "Bearer "))
+	}
+
+	return ""
+}
+
+func toProtoService(s *Service) *registrypb.Service {
+	return &registrypb.Service{
+		Name:     s.Name,
+		Hash:     s.Hash,
+		Callback: s.Callback,
+		Status:   string(s.Status),
+		Address:  s.Address,
+		Port:     int32(s.Port),
+		Tags:     s.Tags,
+		Metadata: s.Metadata,
+	}
+}
+
+// FromProtoService converts a service received over gRPC.
+func FromProtoService(s *registrypb.Service) *Service {
+	return &Service{
+		Name:     s.GetName(),
+		Hash:     s.GetHash(),
+		Callback: s.GetCallback(),
+		Status:   HealthStatus(s.GetStatus()),
+		Address:  s.GetAddress(),
+		Port:     int(s.GetPort()),
+		Tags:     s.GetTags(),
+		Metadata: s.GetMetadata(),
+	}
+}
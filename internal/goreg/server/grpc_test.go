@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/registrypb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func setupGRPCServer(t *testing.T, cfg ServerConfig) (*Server, registrypb.RegistryClient) {
+	t.Helper()
+
+	httpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcLn := bufconn.Listen(1 << 20)
+
+	cfg.GRPC.Enabled = true
+	server, err := NewServer(cfg, WithLogger(zap.NewNop()), WithListener(httpLn), WithGRPCListener(grpcLn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return grpcLn.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+
+	return server, registrypb.NewRegistryClient(conn)
+}
+
+func TestGRPC_RegisterGetRenewDeregister(t *testing.T) {
+	_, client := setupGRPCServer(t, ServerConfig{Port: 8079})
+	ctx := context.Background()
+
+	registered, err := client.Register(ctx, &registrypb.RegisterRequest{
+		Name:     "orders",
+		Callback: "http://orders:8080/callback",
+		Tags:     []string{"v2"},
+	})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	service, err := client.Get(ctx, &registrypb.GetRequest{Name: "orders"})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if service.GetHash() != registered.GetHash() || service.GetTags()[0] != "v2" {
+		t.Errorf("unexpected service: %v", service)
+	}
+
+	if _, err := client.Renew(ctx, &registrypb.RenewRequest{Name: "orders", Hash: registered.GetHash()}); err != nil {
+		t.Errorf("renew: %v", err)
+	}
+
+	if _, err := client.Renew(ctx, &registrypb.RenewRequest{Name: "orders", Hash: "stale"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for a stale hash, got %v", err)
+	}
+
+	list, err := client.List(ctx, &registrypb.ListRequest{})
+	if err != nil || len(list.GetServices()) != 1 {
+		t.Fatalf("list: %v, %v", list, err)
+	}
+
+	if _, err := client.Deregister(ctx, &registrypb.DeregisterRequest{Name: "orders", Hash: "stale"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for a stale hash, got %v", err)
+	}
+
+	if _, err := client.Deregister(ctx, &registrypb.DeregisterRequest{Name: "orders", Hash: registered.GetHash()}); err != nil {
+		t.Fatalf("deregister: %v", err)
+	}
+
+	if _, err := client.Get(ctx, &registrypb.GetRequest{Name: "orders"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound after deregister, got %v", err)
+	}
+}
+
+func TestGRPC_Errors(t *testing.T) {
+	cfg := ServerConfig{Port: 8079, ACL: ACLConfig{
+		Enabled:       true,
+		DefaultPolicy: ACLPolicyDeny,
+		Policies: []ACLPolicy{{
+			Name:  "orders",
+			Rules: []ACLRule{{Service: "orders", Capabilities: []Capability{CapabilityRegister}}},
+		}},
+		Tokens: []ACLToken{{ID: "orders", Secret: "orders-secret", Policies: []string{"orders"}}},
+	}}
+	_, client := setupGRPCServer(t, cfg)
+
+	authorized := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer orders-secret")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  *registrypb.RegisterRequest
+		code codes.Code
+	}{
+		{"invalid", authorized, &registrypb.RegisterRequest{Name: "orders"}, codes.InvalidArgument},
+		{"no token", context.Background(), &registrypb.RegisterRequest{Name: "orders", Callback: "http://orders"}, codes.PermissionDenied},
+		{"other service", authorized, &registrypb.RegisterRequest{Name: "billing", Callback: "http://billing"}, codes.PermissionDenied},
+		{"ok", authorized, &registrypb.RegisterRequest{Name: "orders", Callback: "http://orders"}, codes.OK},
+		{"duplicate", authorized, &registrypb.RegisterRequest{Name: "orders", Callback: "http://orders"}, codes.AlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Register(tt.ctx, tt.req)
+			if got := status.Code(err); got != tt.code {
+				t.Errorf("unexpected code: got %v want %v (%v)", got, tt.code, err)
+			}
+		})
+	}
+}
+
+func TestGRPC_Watch(t *testing.T) {
+	server, client := setupGRPCServer(t, ServerConfig{Port: 8079})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &registrypb.WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil || len(first.GetServices()) != 0 {
+		t.Fatalf("expected empty initial list, got %v, %v", first, err)
+	}
+
+	server.store.Set("orders", "http://orders:8080/callback")
+
+	second, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.GetServices()) != 1 || second.GetServices()[0].GetName() != "orders" {
+		t.Errorf("unexpected update: %v", second)
+	}
+}
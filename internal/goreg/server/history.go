@@ -0,0 +1,278 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+	"go.uber.org/zap"
+)
+
+type InstanceState string
+
+const (
+	StateRegistered   InstanceState = "registered"
+	StatePassing      InstanceState = "passing"
+	StateCritical     InstanceState = "critical"
+	StateDeregistered InstanceState = "deregistered"
+	StateEvicted      InstanceState = "evicted"
+)
+
+type Transition struct {
+	Time  time.Time     `json:"time"`
+	State InstanceState `json:"state"`
+}
+
+// InstanceHistory is the latest transitions of one instance, oldest first.
+// Health transitions are the observed check results, before dampening.
+type InstanceHistory struct {
+	Name        string       `json:"name"`
+	Hash        string       `json:"hash"`
+	Flapping    bool         `json:"flapping"`
+	Transitions []Transition `json:"transitions"`
+}
+
+type FlappingInstance struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+	// Changes is the number of health changes within the flap window.
+	Changes int       `json:"changes"`
+	Since   time.Time `json:"since"`
+}
+
+// maxRemovedHistories bounds the histories kept for instances that are no
+// longer registered.
+const maxRemovedHistories = 1024
+
+// History keeps the transitions of every instance and detects flapping. While
+// an instance flaps its status is held critical, so it stays out of
+// discovery until its health has been stable for the flap window.
+type History struct {
+	mu        sync.Mutex
+	logger    *zap.Logger
+	clock     clock.Clock
+	size      int
+	window    time.Duration
+	threshold int
+	instances map[string]*instanceHistory
+	removed   []string
+}
+
+type instanceHistory struct {
+	name          string
+	hash          string
+	transitions   []Transition
+	changes       []time.Time
+	observed      HealthStatus
+	flappingSince time.Time
+	removed       bool
+}
+
+func NewHistory(cfg HistoryConfig, clk clock.Clock, logger *zap.Logger) *History {
+	size := cfg.Size
+	if size == 0 {
+		size = DefaultHistorySize
+	}
+
+	window := cfg.FlapWindow
+	if window == 0 {
+		window = DefaultFlapWindow
+	}
+
+	threshold := cfg.FlapThreshold
+	if threshold == 0 {
+		threshold = DefaultFlapThreshold
+	}
+
+	return &History{
+		logger:    logger,
+		clock:     clk,
+		size:      size,
+		window:    window,
+		threshold: threshold,
+		instances: make(map[string]*instanceHistory),
+	}
+}
+
+// Registered starts the history of a new instance.
+func (h *History) Registered(name, hash string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.instance(name, hash).add(h.size, Transition{Time: h.clock.Now(), State: StateRegistered})
+}
+
+// Removed ends the history of an instance with StateDeregistered or
+// StateEvicted.
+func (h *History) Removed(name, hash string, state InstanceState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	inst := h.instance(name, hash)
+	if inst.removed {
+		return
+	}
+
+	inst.add(h.size, Transition{Time: h.clock.Now(), State: state})
+	inst.removed = true
+	inst.flappingSince = time.Time{}
+
+	h.removed = append(h.removed, hash)
+	if len(h.removed) > maxRemovedHistories {
+		delete(h.instances, h.removed[0])
+		h.removed = h.removed[1:]
+	}
+}
+
+// Observe records a health check result and returns the status to store:
+// status itself, or critical while the instance flaps.
+func (h *History) Observe(name, hash string, status HealthStatus) HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	inst := h.instance(name, hash)
+	if inst.removed {
+		return status
+	}
+
+	now := h.clock.Now()
+	inst.prune(now.Add(-h.window))
+
+	if status != inst.observed {
+		inst.observed = status
+		inst.changes = append(inst.changes, now)
+		inst.add(h.size, Transition{Time: now, State: InstanceState(status)})
+	}
+
+	flapping := len(inst.changes) >= h.threshold
+	switch {
+	case flapping && inst.flappingSince.IsZero():
+		inst.flappingSince = now
+		h.logger.Warn("goreg->[server]: service {" + name + "} is flapping, holding it critical")
+	case !flapping && !inst.flappingSince.IsZero():
+		inst.flappingSince = time.Time{}
+		h.logger.Info("goreg->[server]: service {" + name + "} stopped flapping")
+	}
+
+	if flapping {
+		return StatusCritical
+	}
+	return status
+}
+
+// Get returns the history of the instance with hash.
+func (h *History) Get(hash string) (InstanceHistory, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	inst, ok := h.instances[hash]
+	if !ok {
+		return InstanceHistory{}, false
+	}
+
+	return InstanceHistory{
+		Name:        inst.name,
+		Hash:        inst.hash,
+		Flapping:    !inst.flappingSince.IsZero(),
+		Transitions: append([]Transition(nil), inst.transitions...),
+	}, true
+}
+
+// Flapping lists the registered instances that currently flap, by name.
+func (h *History) Flapping() []FlappingInstance {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	flapping := make([]FlappingInstance, 0)
+	for _, inst := range h.instances {
+		if inst.flappingSince.IsZero() {
+			continue
+		}
+
+		flapping = append(flapping, FlappingInstance{
+			Name:    inst.name,
+			Hash:    inst.hash,
+			Changes: len(inst.changes),
+			Since:   inst.flappingSince,
+		})
+	}
+
+	sort.Slice(flapping, func(i, j int) bool {
+		return flapping[i].Name < flapping[j].Name
+	})
+	return flapping
+}
+
+// instance returns the history of hash, starting it for instances registered
+// before the history was.
+func (h *History) instance(name, hash string) *instanceHistory {
+	inst, ok := h.instances[hash]
+	if !ok {
+		inst = &instanceHistory{name: name, hash: hash, observed: StatusPassing}
+		h.instances[hash] = inst
+	}
+	return inst
+}
+
+func (i *instanceHistory) add(size int, t Transition) {
+	if len(i.transitions) == size {
+		i.transitions = append(i.transitions[:0], i.transitions[1:]...)
+	}
+	i.transitions = append(i.transitions, t)
+}
+
+// prune forgets the health changes before cutoff.
+func (i *instanceHistory) prune(cutoff time.Time) {
+	keep := 0
+	for keep < len(i.changes) && i.changes[keep].Before(cutoff) {
+		keep++
+	}
+	i.changes = i.changes[keep:]
+}
+
+// HistoryV1Handler returns the transition history of one instance, which
+// outlives its registration.
+func (g *Server) HistoryV1Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, "GET")
+		return
+	}
+
+	name, id := r.PathValue("name"), r.PathValue("id")
+	if !g.allowedV1(w, r, name, CapabilityRead) {
+		return
+	}
+
+	var history InstanceHistory
+	ok := false
+	if g.history != nil {
+		history, ok = g.history.Get(id)
+	}
+	if !ok || history.Name != name {
+		writeProblem(w, r, http.StatusNotFound, CodeInstanceNotFound, "service "+name+" has no history for instance "+id)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// FlappingV1Handler lists the readable instances that currently flap.
+func (g *Server) FlappingV1Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, "GET")
+		return
+	}
+
+	flapping := make([]FlappingInstance, 0)
+	if g.history != nil {
+		for _, inst := range g.history.Flapping() {
+			if g.allowed(r, inst.Name, CapabilityRead) {
+				flapping = append(flapping, inst)
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, flapping)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+	"go.uber.org/zap"
+)
+
+func TestHistory_Flapping(t *testing.T) {
+	manual := clock.NewManual(time.Now())
+	history := NewHistory(HistoryConfig{Size: 4, FlapWindow: time.Minute, FlapThreshold: 3}, manual, zap.NewNop())
+	history.Registered("orders", "abc")
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		status  HealthStatus
+		want    HealthStatus
+	}{
+		{"first failure", time.Second, StatusCritical, StatusCritical},
+		{"recovery", time.Second, StatusPassing, StatusPassing},
+		{"third change flaps", time.Second, StatusCritical, StatusCritical},
+		{"held critical", time.Second, StatusPassing, StatusCritical},
+		{"stable again", 2 * time.Minute, StatusPassing, StatusPassing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manual.Advance(tt.advance)
+			if got := history.Observe("orders", "abc", tt.status); got != tt.want {
+				t.Errorf("Observe() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if flapping := history.Flapping(); len(flapping) != 0 {
+		t.Errorf("expected no flapping instances, got %+v", flapping)
+	}
+
+	got, ok := history.Get("abc")
+	if !ok {
+		t.Fatal("expected a history for abc")
+	}
+
+	want := []InstanceState{StateCritical, StatePassing, StateCritical, StatePassing}
+	if len(got.Transitions) != len(want) {
+		t.Fatalf("unexpected transitions: %+v", got.Transitions)
+	}
+	for i, transition := range got.Transitions {
+		if transition.State != want[i] {
+			t.Errorf("transition %d is %s, want %s", i, transition.State, want[i])
+		}
+	}
+}
+
+func TestHistory_Removed(t *testing.T) {
+	history := NewHistory(HistoryConfig{}, clock.NewManual(time.Now()), zap.NewNop())
+	history.Registered("orders", "abc")
+	history.Removed("orders", "abc", StateEvicted)
+
+	if status := history.Observe("orders", "abc", StatusCritical); status != StatusCritical {
+		t.Errorf("unexpected status %s", status)
+	}
+
+	got, _ := history.Get("abc")
+	if len(got.Transitions) != 2 || got.Transitions[1].State != StateEvicted {
+		t.Errorf("unexpected transitions: %+v", got.Transitions)
+	}
+}
+
+type toggleHTTPClient struct {
+	fail bool
+}
+
+func (c *toggleHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if c.fail {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestFlappingV1Handler(t *testing.T) {
+	probe := &toggleHTTPClient{}
+	server, err := NewServer(ServerConfig{Port: 8080, History: HistoryConfig{FlapThreshold: 2}},
+		WithLogger(zap.NewNop()), WithHTTPClient(probe), WithClock(clock.NewManual(time.Now())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.Handler()
+
+	created := serveV1(t, handler, http.MethodPost, "/v1/services", Service{Name: "orders", Callback: "http://orders:8080/callback"})
+	var orders Service
+	json.NewDecoder(created.Body).Decode(&orders)
+
+	for _, fail := range []bool{true, false} {
+		probe.fail = fail
+		server.checkServiceAvailability(orders)
+	}
+
+	if service, _ := server.store.Get("orders"); service.Status != StatusCritical {
+		t.Errorf("flapping service should be held critical, is %s", service.Status)
+	}
+
+	var flapping []FlappingInstance
+	json.NewDecoder(serveV1(t, handler, http.MethodGet, "/v1/flapping", nil).Body).Decode(&flapping)
+	if len(flapping) != 1 || flapping[0].Hash != orders.Hash || flapping[0].Changes != 2 {
+		t.Errorf("unexpected flapping instances: %+v", flapping)
+	}
+
+	serveV1(t, handler, http.MethodDelete, "/v1/services/orders", nil)
+
+	rr := serveV1(t, handler, http.MethodGet, "/v1/services/orders/instances/"+orders.Hash+"/history", nil)
+	var history InstanceHistory
+	json.NewDecoder(rr.Body).Decode(&history)
+
+	want := []InstanceState{StateRegistered, StateCritical, StatePassing, StateDeregistered}
+	if rr.Code != http.StatusOK || len(history.Transitions) != len(want) {
+		t.Fatalf("unexpected history %d: %+v", rr.Code, history)
+	}
+	for i, transition := range history.Transitions {
+		if transition.State != want[i] {
+			t.Errorf("transition %d is %s, want %s", i, transition.State, want[i])
+		}
+	}
+
+	if rr := serveV1(t, handler, http.MethodGet, "/v1/services/billing/instances/"+orders.Hash+"/history", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("history under another name returned %d", rr.Code)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "goreg"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	registrations   prometheus.Counter
+	deregistrations prometheus.Counter
+	checkDuration   *prometheus.HistogramVec
+	checkFailures   prometheus.Counter
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+}
+
+func NewMetrics(store *ServerStore) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "registrations_total",
+			Help:      "Number of successful service registrations.",
+		}),
+		deregistrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "deregistrations_total",
+			Help:      "Number of successful service deregistrations.",
+		}),
+		checkDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "health_check_duration_seconds",
+			Help:      "Latency of callback health checks.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"status"}),
+		checkFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "health_check_failures_total",
+			Help:      "Number of failed callback health checks.",
+		}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by handler, method and status code.",
+		}, []string{"handler", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by handler.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"handler", "method", "code"}),
+	}
+
+	m.registry.MustRegister(
+		m.registrations,
+		m.deregistrations,
+		m.checkDuration,
+		m.checkFailures,
+		m.httpRequests,
+		m.httpDuration,
+		newStoreCollector(store),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// instrument wraps a handler with request counters and latencies labelled
+// with the route name rather than the raw path.
+func (m *Metrics) instrument(name string, next http.HandlerFunc) http.Handler {
+	labels := prometheus.Labels{"handler": name}
+	return promhttp.InstrumentHandlerCounter(
+		m.httpRequests.MustCurryWith(labels),
+		promhttp.InstrumentHandlerDuration(m.httpDuration.MustCurryWith(labels), next),
+	)
+}
+
+func (m *Metrics) registered() {
+	if m != nil {
+		m.registrations.Inc()
+	}
+}
+
+func (m *Metrics) deregistered() {
+	if m != nil {
+		m.deregistrations.Inc()
+	}
+}
+
+func (m *Metrics) checked(status HealthStatus, seconds float64) {
+	if m == nil {
+		return
+	}
+
+	m.checkDuration.WithLabelValues(string(status)).Observe(seconds)
+	if status != StatusPassing {
+		m.checkFailures.Inc()
+	}
+}
+
+// storeCollector reports the store content at scrape time, so gauges never
+// drift from the actual registrations.
+type storeCollector struct {
+	store     *ServerStore
+	services  *prometheus.Desc
+	instances *prometheus.Desc
+}
+
+func newStoreCollector(store *ServerStore) *storeCollector {
+	return &storeCollector{
+		store: store,
+		services: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "services"),
+			"Number of distinct registered service names.",
+			nil, nil,
+		),
+		instances: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "service_instances"),
+			"Number of registered service instances by health status.",
+			[]string{"status"}, nil,
+		),
+	}
+}
+
+func (c *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.services
+	ch <- c.instances
+}
+
+func (c *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	names := make(map[string]bool)
+	byStatus := map[HealthStatus]int{
+		StatusPassing:  0,
+		StatusCritical: 0,
+	}
+
+	for _, service := range c.store.GetAll() {
+		names[service.Name] = true
+		byStatus[service.Status]++
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.services, prometheus.GaugeValue, float64(len(names)))
+	for status, count := range byStatus {
+		ch <- prometheus.MustNewConstMetric(c.instances, prometheus.GaugeValue, float64(count), string(status))
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+type failingHTTPClient struct{}
+
+func (failingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("metrics returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	body, _ := io.ReadAll(rr.Body)
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	server, err := NewServer(ServerConfig{Port: 8080}, WithLogger(zap.NewNop()), WithHTTPClient(failingHTTPClient{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.Handler()
+
+	for _, name := range []string{"orders", "billing"} {
+		body, _ := json.Marshal(Service{Name: name, Callback: "http://" + name + ":8080/callback"})
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/set", bytes.NewBuffer(body)))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/delete?name=billing", nil))
+
+	orders, _ := server.store.Get("orders")
+	server.checkServiceAvailability(*orders)
+
+	metrics := scrape(t, handler)
+
+	for _, want := range []string{
+		"goreg_registrations_total 2",
+		"goreg_deregistrations_total 1",
+		"goreg_services 1",
+		`goreg_service_instances{status="critical"} 1`,
+		`goreg_service_instances{status="passing"} 0`,
+		"goreg_health_check_failures_total 1",
+		`goreg_health_check_duration_seconds_count{status="critical"} 1`,
+		`goreg_http_requests_total{code="201",handler="/set",method="post"} 2`,
+		`goreg_http_request_duration_seconds_count{code="204",handler="/delete",method="delete"} 1`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPI is the OpenAPI 3 document of the HTTP API. Every route registered
+// by Handler but the dashboard assets is described in it; the contract tests
+// keep both in sync.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+func (g *Server) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if err := ValidateHttpMethod(r.Method, http.MethodGet); err != nil {
+		http.Error(w, err.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPI)
+}
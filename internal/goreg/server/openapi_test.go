@@ -0,0 +1,128 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/Danis0n/goreg/internal/goreg/openapitest"
+	"github.com/Danis0n/goreg/internal/goreg/server"
+	"go.uber.org/zap"
+)
+
+func TestOpenAPIContract(t *testing.T) {
+	spec := openapitest.Load(t)
+
+	srv, err := server.NewServer(server.ServerConfig{Port: 8079, ACL: server.ACLConfig{
+		Enabled:       true,
+		DefaultPolicy: server.ACLPolicyDeny,
+		Policies: []server.ACLPolicy{{
+			Name:  "admin",
+			Rules: []server.ACLRule{{Service: "*", Capabilities: []server.Capability{server.CapabilityAdmin}}},
+		}},
+		Tokens: []server.ACLToken{{ID: "root", Secret: "root-secret", Policies: []string{"admin"}}},
+	}}, server.WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := srv.Handler()
+
+	var orders server.Service
+	policy := server.ACLPolicy{Name: "readers", Rules: []server.ACLRule{{Service: "*", Capabilities: []server.Capability{server.CapabilityRead}}}}
+
+	steps := []struct {
+		method string
+		target func() string
+		body   any
+		token  string
+		code   int
+		out    any
+	}{
+		{http.MethodGet, path("/health"), nil, "", http.StatusOK, nil},
+		{http.MethodGet, path("/openapi.json"), nil, "", http.StatusOK, nil},
+		{http.MethodGet, path("/metrics"), nil, "", http.StatusOK, nil},
+		{http.MethodPost, path("/v1/services"), server.Service{Name: "orders", Callback: "http://orders:8080/callback", Tags: []string{"v1"}}, "root-secret", http.StatusCreated, &orders},
+		{http.MethodPost, path("/v1/services"), server.Service{Name: "orders", Callback: "http://orders:8080/callback"}, "root-secret", http.StatusConflict, nil},
+		{http.MethodPost, path("/v1/services"), server.Service{Name: "payments", Callback: "ftp://payments"}, "root-secret", http.StatusBadRequest, nil},
+		{http.MethodPost, path("/v1/services"), server.Service{Name: "billing", Callback: "http://billing"}, "", http.StatusForbidden, nil},
+		{http.MethodPost, path("/v1/services"), server.Service{Name: "billing", Callback: "http://billing"}, "root-secret", http.StatusCreated, nil},
+		{http.MethodGet, path("/v1/services?status=passing"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodGet, path("/v1/services?index=0&wait=1s"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodGet, path("/v1/services?index=1&wait=soon"), nil, "root-secret", http.StatusBadRequest, nil},
+		{http.MethodGet, path("/v1/services/orders"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodPut, func() string { return "/v1/services/orders?cas=" + strconv.FormatUint(orders.ModifyIndex, 10) }, server.Service{Callback: "http://orders:9090/callback"}, "root-secret", http.StatusOK, nil},
+		{http.MethodPut, func() string { return "/v1/services/orders?cas=" + strconv.FormatUint(orders.ModifyIndex, 10) }, server.Service{Callback: "http://orders:9090/callback"}, "root-secret", http.StatusConflict, nil},
+		{http.MethodGet, path("/v1/services/missing"), nil, "root-secret", http.StatusNotFound, nil},
+		{http.MethodGet, path("/v1/services/orders/instances"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodGet, func() string { return "/v1/services/orders/instances/" + orders.Hash }, nil, "root-secret", http.StatusOK, nil},
+		{http.MethodDelete, func() string { return "/v1/services/orders/instances/" + orders.Hash }, nil, "root-secret", http.StatusNoContent, nil},
+		{http.MethodGet, func() string { return "/v1/services/orders/instances/" + orders.Hash + "/history" }, nil, "root-secret", http.StatusOK, nil},
+		{http.MethodGet, path("/v1/services/orders/instances/missing/history"), nil, "root-secret", http.StatusNotFound, nil},
+		{http.MethodGet, path("/v1/flapping"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodDelete, path("/v1/services/billing"), nil, "root-secret", http.StatusNoContent, nil},
+		{http.MethodDelete, path("/v1/services/billing"), nil, "root-secret", http.StatusNotFound, nil},
+		{http.MethodPost, path("/v1/batch"), batch(server.BatchOp{Verb: server.BatchRegister, Service: server.Service{Name: "users", Callback: "http://users"}}), "root-secret", http.StatusOK, nil},
+		{http.MethodPost, path("/v1/batch"), batch(server.BatchOp{Verb: server.BatchRegister, Service: server.Service{Name: "users", Callback: "http://users"}}), "root-secret", http.StatusConflict, nil},
+		{http.MethodPost, path("/v1/batch"), batch(server.BatchOp{Verb: server.BatchRenew, Service: server.Service{Name: "users", Hash: "stale"}}), "root-secret", http.StatusNotFound, nil},
+		{http.MethodPost, path("/v1/batch"), batch(server.BatchOp{Verb: server.BatchDeregister, Service: server.Service{Name: "users"}}), "", http.StatusForbidden, nil},
+		{http.MethodPost, path("/v1/batch"), batch(server.BatchOp{Verb: server.BatchRenew, Service: server.Service{Name: "users"}}), "root-secret", http.StatusBadRequest, nil},
+		{http.MethodPost, path("/v1/batch"), batch(server.BatchOp{Verb: server.BatchDeregister, Service: server.Service{Name: "users"}}), "root-secret", http.StatusOK, nil},
+		{http.MethodPost, path("/set"), server.Service{Name: "search", Callback: "http://search"}, "root-secret", http.StatusCreated, nil},
+		{http.MethodPost, path("/set?cas=1"), server.Service{Name: "search", Callback: "http://search"}, "root-secret", http.StatusConflict, nil},
+		{http.MethodGet, path("/get?name=search"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodGet, path("/getall"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodGet, path("/prometheus/sd"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodDelete, path("/delete?name=search"), nil, "root-secret", http.StatusNoContent, nil},
+		{http.MethodGet, path("/v1/audit?service=orders&action=register"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodGet, path("/v1/audit?format=jsonl"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodGet, path("/v1/audit"), nil, "", http.StatusForbidden, nil},
+		{http.MethodPut, path("/acl/policies"), policy, "root-secret", http.StatusNoContent, nil},
+		{http.MethodPost, path("/acl/policies"), policy, "root-secret", http.StatusNoContent, nil},
+		{http.MethodGet, path("/acl/policies"), nil, "root-secret", http.StatusOK, nil},
+		{http.MethodDelete, path("/acl/policies?name=readers"), nil, "root-secret", http.StatusNoContent, nil},
+	}
+
+	for _, step := range steps {
+		target := step.target()
+
+		var body io.Reader
+		if step.body != nil {
+			data, _ := json.Marshal(step.body)
+			body = bytes.NewReader(data)
+		}
+
+		req := httptest.NewRequest(step.method, target, body)
+		if step.body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if step.token != "" {
+			req.Header.Set("Authorization", "Bearer "+step.token)
+		}
+
+		rr := spec.Serve(t, handler, req)
+		if rr.Code != step.code {
+			t.Errorf("%s %s returned %d, want %d: %s", step.method, target, rr.Code, step.code, rr.Body)
+		}
+		if step.out != nil {
+			json.NewDecoder(rr.Body).Decode(step.out)
+		}
+	}
+
+	if missing := spec.Uncovered(); len(missing) > 0 {
+		sort.Strings(missing)
+		t.Errorf("documented operations without a contract check: %v", missing)
+	}
+}
+
+func path(p string) func() string {
+	return func() string { return p }
+}
+
+func batch(ops ...server.BatchOp) server.BatchRequest {
+	return server.BatchRequest{Operations: ops}
+}
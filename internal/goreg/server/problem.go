@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Danis0n/goreg/internal/goreg/validation"
+)
+
+const problemContentType = "application/problem+json"
+
+// Machine readable problem codes of the /v1 API.
+const (
+	CodeInvalidBody        = "invalid_body"
+	CodeValidationFailed   = "validation_failed"
+	CodeServiceNotFound    = "service_not_found"
+	CodeInstanceNotFound   = "instance_not_found"
+	CodeServiceExists      = "service_exists"
+	CodeInvalidQuery       = "invalid_query"
+	CodeIndexConflict      = "index_conflict"
+	CodePreconditionFailed = "precondition_failed"
+	CodePermissionDenied   = "permission_denied"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeNotFound           = "not_found"
+	CodeInternal           = "internal"
+	CodeBatchAborted       = "batch_aborted"
+)
+
+// Problem is an RFC 9457 problem details body. Code repeats the last segment
+// of Type so clients can switch on it without parsing URIs.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          string         `json:"code"`
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+func newProblem(r *http.Request, status int, code, detail string) Problem {
+	return Problem{
+		Type:     "urn:goreg:problem:" + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	encodeProblem(w, newProblem(r, status, code, detail))
+}
+
+// writeValidationProblem lists every invalid field of err.
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := newProblem(r, http.StatusBadRequest, CodeValidationFailed, err.Error())
+	for _, fieldErr := range validation.FieldErrors(err) {
+		problem.InvalidParams = append(problem.InvalidParams, InvalidParam{
+			Name:   fieldErr.Field,
+			Code:   validation.Code(fieldErr),
+			Reason: fieldErr.Error(),
+		})
+	}
+	encodeProblem(w, problem)
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeProblem(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed, use "+allowed)
+}
+
+func encodeProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const sdLabelPrefix = "__meta_goreg_"
+
+// TargetGroup is one entry of the Prometheus http_sd_config response.
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// PrometheusSDHandler renders the registry as Prometheus HTTP service
+// discovery targets. Only passing instances are listed unless ?all=true is
+// given; ?name= limits the output to one service.
+func (g *Server) PrometheusSDHandler(w http.ResponseWriter, r *http.Request) {
+	if err := ValidateHttpMethod(r.Method, http.MethodGet); err != nil {
+		http.Error(w, err.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	all, _ := strconv.ParseBool(query.Get("all"))
+	name := query.Get("name")
+
+	services := g.readable(r, g.store.GetAll())
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	groups := make([]TargetGroup, 0, len(services))
+	for _, service := range services {
+		if name != "" && service.Name != name {
+			continue
+		}
+		if !all && service.Status != StatusPassing {
+			continue
+		}
+
+		target, err := service.Target()
+		if err != nil {
+			g.logger.Warn("goreg->[server]: no scrape target for {" + service.Name + "}: " + err.Error())
+			continue
+		}
+
+		groups = append(groups, TargetGroup{
+			Targets: []string{target},
+			Labels:  targetLabels(service),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// targetLabels follows the Consul SD conventions so existing relabel rules
+// carry over: tags are joined with a leading and trailing separator.
+func targetLabels(service *Service) map[string]string {
+	labels := map[string]string{
+		sdLabelPrefix + "service": service.Name,
+		sdLabelPrefix + "status":  string(service.Status),
+	}
+
+	if len(service.Tags) > 0 {
+		labels[sdLabelPrefix+"tags"] = "," + strings.Join(service.Tags, ",") + ","
+	}
+
+	for key, value := range service.Metadata {
+		labels[sdLabelPrefix+"metadata_"+sanitizeLabelName(key)] = value
+	}
+
+	return labels
+}
+
+// sanitizeLabelName replaces every character not allowed in a Prometheus label
+// name with an underscore.
+func sanitizeLabelName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestPrometheusSDHandler(t *testing.T) {
+	server := setupTestServer()
+
+	server.store.SetService(Service{
+		Name:     "orders",
+		Callback: "http://orders.internal:8080/callback",
+		Port:     9100,
+		Tags:     []string{"v2", "eu"},
+		Metadata: map[string]string{"team": "checkout", "scrape-path": "/metrics"},
+	})
+	server.store.SetService(Service{
+		Name:     "billing",
+		Callback: "https://billing.internal/callback",
+		Address:  "10.0.0.7",
+	})
+	server.store.Set("search", "http://search:8080/callback")
+	server.store.SetStatus("search", StatusCritical)
+
+	tests := []struct {
+		name    string
+		query   string
+		targets []string
+	}{
+		{"healthy only", "", []string{"10.0.0.7:443", "orders.internal:9100"}},
+		{"all", "?all=true", []string{"10.0.0.7:443", "orders.internal:9100", "search:8080"}},
+		{"by name", "?name=orders", []string{"orders.internal:9100"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			server.PrometheusSDHandler(rr, httptest.NewRequest(http.MethodGet, "/prometheus/sd"+tt.query, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+
+			var groups []TargetGroup
+			if err := json.NewDecoder(rr.Body).Decode(&groups); err != nil {
+				t.Fatal(err)
+			}
+
+			var targets []string
+			for _, group := range groups {
+				targets = append(targets, group.Targets...)
+			}
+			if !reflect.DeepEqual(targets, tt.targets) {
+				t.Errorf("unexpected targets: got %v want %v", targets, tt.targets)
+			}
+		})
+	}
+}
+
+func TestTargetLabels(t *testing.T) {
+	labels := targetLabels(&Service{
+		Name:     "orders",
+		Status:   StatusPassing,
+		Tags:     []string{"v2", "eu"},
+		Metadata: map[string]string{"scrape-path": "/metrics"},
+	})
+
+	want := map[string]string{
+		"__meta_goreg_service":              "orders",
+		"__meta_goreg_status":               "passing",
+		"__meta_goreg_tags":                 ",v2,eu,",
+		"__meta_goreg_metadata_scrape_path": "/metrics",
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("unexpected labels: got %v want %v", labels, want)
+	}
+}
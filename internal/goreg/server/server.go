@@ -1,14 +1,28 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Danis0n/goreg/internal/goreg/clock"
 	"github.com/Danis0n/goreg/internal/goreg/httpprovider"
+	"github.com/Danis0n/goreg/internal/goreg/tlsprovider"
+	"github.com/Danis0n/goreg/internal/goreg/tracing"
+	"github.com/Danis0n/goreg/internal/goreg/validation"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 type Server struct {
@@ -17,51 +31,330 @@ type Server struct {
 	errch       chan error
 	closeCh     chan struct{}
 	closeDoneCh chan struct{}
+	closeOnce   sync.Once
+	started     atomic.Bool
 	port        int
+	interval    time.Duration
 	httpClient  httpprovider.HttpClient
+	httpServer  *http.Server
+	listener    net.Listener
+	clock       clock.Clock
+	tls         *tlsprovider.Reloader
+	acl         *ACL
+	metrics     *Metrics
+	tracer      trace.Tracer
+	dns         *DNSServer
+	dnsConn     net.PacketConn
+	grpc        *grpc.Server
+	grpcPort    int
+	grpcLn      net.Listener
+	audit       *AuditLog
+	history     *History
+	evictAfter  time.Duration
+	ui          bool
+	criticalMu  sync.Mutex
+	critical    map[string]criticalSince
 }
 
-func NewServer(cfg ServerConfig) (*Server, error) {
+// criticalSince is when a registration, told apart by its hash, turned
+// critical.
+type criticalSince struct {
+	hash  string
+	since time.Time
+}
+
+type RegisterResponse struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+type HealthResponse struct {
+	Status   string `json:"status"`
+	Services int    `json:"services"`
+}
+
+const probeTimeout = 10 * time.Second
+
+func NewServer(cfg ServerConfig, opts ...Option) (*Server, error) {
 	if err := ValidateServerConfig(cfg); err != nil {
 		return nil, err
 	}
 
-	logger, err := zap.NewDevelopment()
+	o, err := newOptions(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	stor, err := NewServerStore(logger)
+	stor := o.store
+	if stor == nil {
+		if stor, err = NewServerStore(o.logger); err != nil {
+			return nil, err
+		}
+	}
+
+	var acl *ACL
+	if cfg.ACL.Enabled {
+		if acl, err = NewACL(cfg.ACL); err != nil {
+			return nil, err
+		}
+	}
+
+	interval := cfg.CheckInterval
+	if interval == 0 {
+		interval = DefaultCheckInterval
+	}
+
+	// The registry has always required client certificates signed by ca_file;
+	// client_ca_file only sets a different CA for them.
+	tlsCfg := cfg.TLS
+	if tlsCfg.ClientCAFile == "" {
+		tlsCfg.ClientCAFile = tlsCfg.CAFile
+	}
+
+	var reloader *tlsprovider.Reloader
+	if tlsCfg.Enabled() {
+		if reloader, err = tlsprovider.NewReloader(tlsCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	httpClient := o.httpClient
+	if httpClient == nil {
+		client := &http.Client{Timeout: probeTimeout}
+		if reloader != nil {
+			client = reloader.HTTPClient()
+			client.Timeout = probeTimeout
+		}
+		httpClient = client
+	}
+
+	audit, err := NewAuditLog(cfg.Audit, o.clock, o.logger)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Server{
-		logger:      logger,
+	var dnsServer *DNSServer
+	if cfg.DNS.Enabled {
+		dnsServer = NewDNSServer(cfg.DNS, stor, o.logger)
+	}
+
+	srv := &Server{
+		logger:      o.logger,
 		store:       stor,
 		errch:       make(chan error),
 		closeCh:     make(chan struct{}),
 		closeDoneCh: make(chan struct{}),
 		port:        cfg.Port,
-	}, nil
+		interval:    interval,
+		httpClient:  httpClient,
+		listener:    o.listener,
+		clock:       o.clock,
+		tls:         reloader,
+		acl:         acl,
+		metrics:     NewMetrics(stor),
+		tracer:      tracing.Tracer(o.tracerProvider),
+		dns:         dnsServer,
+		dnsConn:     o.dnsConn,
+		grpcLn:      o.grpcListener,
+		audit:       audit,
+		history:     NewHistory(cfg.History, o.clock, o.logger),
+		evictAfter:  cfg.EvictAfter,
+		ui:          cfg.UI.Enabled,
+		critical:    make(map[string]criticalSince),
+	}
+	if cfg.GRPC.Enabled {
+		srv.grpcPort = cfg.GRPC.Port
+		if srv.grpcPort == 0 {
+			srv.grpcPort = DefaultGRPCPort
+		}
+		srv.grpc = srv.newGRPCServer()
+	}
+
+	// The http.Server exists before Start so Shutdown never races with its
+	// creation, and a Shutdown that comes first makes later serving return
+	// http.ErrServerClosed.
+	srv.httpServer = &http.Server{
+		Addr:    ":" + strconv.Itoa(cfg.Port),
+		Handler: srv.Handler(),
+	}
+	if reloader != nil && reloader.Certificate() != nil {
+		srv.httpServer.TLSConfig = reloader.ServerTLSConfig()
+	}
+
+	return srv, nil
 }
 
-func NewServerWithStart(cfg ServerConfig) (*Server, error) {
-	registrator, err := NewServer(cfg)
+func NewServerWithStart(cfg ServerConfig, opts ...Option) (*Server, error) {
+	registrator, err := NewServer(cfg, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	registrator.Start()
+	if err := registrator.Start(); err != nil {
+		return nil, err
+	}
 	return registrator, nil
 }
 
-func (g *Server) Start() {
-	defer close(g.closeDoneCh)
+// Start binds the listener and serves in the background. Listen errors are
+// returned, http.ErrServerClosed after Shutdown; use ListenAndServe to also
+// observe errors that stop serving.
+func (g *Server) Start() error {
+	ln, err := g.listen()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := g.serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			g.logger.Error("goreg->[server]: serve error: " + err.Error())
+		}
+	}()
+	return nil
+}
+
+// ListenAndServe serves until Shutdown is called, in which case it returns
+// nil, or until the listener fails. It returns nil at once after Shutdown.
+func (g *Server) ListenAndServe() error {
+	ln, err := g.listen()
+	if err == nil {
+		err = g.serve(ln)
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (g *Server) closed() bool {
+	select {
+	case <-g.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown stops health checks and drains in-flight requests until ctx is
+// done.
+func (g *Server) Shutdown(ctx context.Context) error {
+	g.closeOnce.Do(func() {
+		close(g.closeCh)
+	})
+
+	var err error
+	if g.httpServer != nil {
+		err = g.httpServer.Shutdown(ctx)
+	}
+
+	if g.dns != nil {
+		err = errors.Join(err, g.dns.Shutdown(ctx))
+	}
+
+	if g.grpc != nil {
+		stopped := make(chan struct{})
+		go func() {
+			g.grpc.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			g.grpc.Stop()
+		}
+	}
+
+	if g.started.Load() {
+		select {
+		case <-g.closeDoneCh:
+		case <-ctx.Done():
+			if !errors.Is(err, ctx.Err()) {
+				err = errors.Join(err, ctx.Err())
+			}
+		}
+	}
+
+	if g.audit != nil {
+		err = errors.Join(err, g.audit.Close())
+	}
+
+	return err
+}
+
+func (g *Server) listen() (net.Listener, error) {
+	if g.closed() {
+		if g.listener != nil {
+			g.listener.Close()
+		}
+		return nil, http.ErrServerClosed
+	}
+
+	ln := g.listener
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", g.httpServer.Addr); err != nil {
+			return nil, errors.New("goreg->[server]: listen: " + err.Error())
+		}
+	}
+
+	if g.dns != nil {
+		if err := g.dns.Start(g.dnsConn); err != nil {
+			ln.Close()
+			return nil, errors.New("goreg->[server]: dns listen: " + err.Error())
+		}
+	}
+
+	if g.grpc != nil {
+		if err := g.serveGRPC(); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
 
-	g.startServer(g.port)
+func (g *Server) serveGRPC() error {
+	ln := g.grpcLn
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", ":"+strconv.Itoa(g.grpcPort)); err != nil {
+			return errors.New("goreg->[server]: grpc listen: " + err.Error())
+		}
+	}
 
+	g.logger.Info("goreg->[server]: grpc was started at: " + ln.Addr().String())
 	go func() {
+		if err := g.grpc.Serve(ln); err != nil {
+			g.logger.Error("goreg->[server]: grpc serve error: " + err.Error())
+		}
+	}()
+	return nil
+}
+
+// serve returns http.ErrServerClosed once Shutdown was called, including when
+// it was called before serve.
+func (g *Server) serve(ln net.Listener) error {
+	g.startChecks()
+
+	if g.httpServer.TLSConfig != nil {
+		g.logger.Info("Server was started with TLS at: " + ln.Addr().String())
+		return g.httpServer.ServeTLS(ln, "", "")
+	}
+
+	g.logger.Info("Server was started at: " + ln.Addr().String())
+	return g.httpServer.Serve(ln)
+}
+
+func (g *Server) startChecks() {
+	if !g.started.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer close(g.closeDoneCh)
+
 		for {
 			select {
 			case <-g.closeCh:
@@ -69,25 +362,82 @@ func (g *Server) Start() {
 				return
 			case err := <-g.errch:
 				g.logger.Error(err.Error())
-			case <-time.After(time.Minute):
+			case <-g.clock.After(g.interval):
 				g.checkServicesAvailability()
 			}
 		}
 	}()
 }
 
-func (g *Server) startServer(port int) error {
-	http.HandleFunc("/set", g.SetHandler)
-	http.HandleFunc("/delete", g.DeleteHandler)
-	http.HandleFunc("/getall", g.GetAllHandler)
-	http.HandleFunc("/get", g.GetHandler)
+func (g *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	g.registerV1(mux)
+
+	// Deprecated aliases of the /v1 API.
+	g.handle(mux, "/set", deprecated(v1Services, g.SetHandler))
+	g.handle(mux, "/delete", deprecated(v1Service, g.DeleteHandler))
+	g.handle(mux, "/getall", deprecated(v1Services, g.GetAllHandler))
+	g.handle(mux, "/get", deprecated(v1Service, g.GetHandler))
+	g.handle(mux, "/acl/policies", g.PoliciesHandler)
+	g.handle(mux, "/health", g.HealthHandler)
+	g.handle(mux, "/prometheus/sd", g.PrometheusSDHandler)
+	g.handle(mux, "/openapi.json", g.OpenAPIHandler)
+
+	if g.ui {
+		g.handle(mux, "/ui/", g.DashboardHandler)
+	}
+
+	if g.metrics != nil {
+		mux.Handle("/metrics", g.metrics.Handler())
+	}
+
+	return mux
+}
+
+func (g *Server) handle(mux *http.ServeMux, pattern string, handler http.HandlerFunc) {
+	var h http.Handler = handler
+	if g.metrics != nil {
+		h = g.metrics.instrument(pattern, handler)
+	}
+	mux.Handle(pattern, tracing.Middleware(g.tracerOrNoop(), pattern, h))
+}
+
+// deprecated marks responses of a legacy route and points to its successor.
+// A {name} in successor is filled from the name query parameter; without one
+// there is no successor to link to.
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+
+		link, ok := successor, true
+		if strings.Contains(successor, "{name}") {
+			name := r.URL.Query().Get("name")
+			link = strings.Replace(successor, "{name}", url.PathEscape(name), 1)
+			ok = name != ""
+		}
+		if ok {
+			w.Header().Set("Link", "<"+link+">; rel=\"successor-version\"")
+		}
 
-	g.logger.Info("Server was started at port: " + strconv.Itoa(port))
-	return http.ListenAndServe(":"+strconv.Itoa(port), nil)
+		next(w, r)
+	}
+}
+
+func (g *Server) tracerOrNoop() trace.Tracer {
+	if g.tracer == nil {
+		return tracing.Tracer(nil)
+	}
+	return g.tracer
+}
+
+// Metrics exposes the Prometheus registry so applications can add their own
+// collectors next to the registry ones.
+func (g *Server) Metrics() *Metrics {
+	return g.metrics
 }
 
 func (g *Server) checkServicesAvailability() {
-	for _, service := range g.store.services {
+	for _, service := range g.store.GetAll() {
 		go func() {
 			g.logger.Info("goreg->[server]: check service availability: " + service.Name)
 			g.checkServiceAvailability(*service)
@@ -96,21 +446,101 @@ func (g *Server) checkServicesAvailability() {
 }
 
 func (g *Server) checkServiceAvailability(service Service) {
-	url := service.Callback + "?hash= " + service.Hash
+	url := service.Callback + "?hash=" + service.Hash
+
+	ctx, span := g.tracerOrNoop().Start(context.Background(), "goreg.health_check",
+		trace.WithAttributes(semconv.ServiceName(service.Name)),
+	)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
+		tracing.End(span, err)
 		g.logger.Error("goreg->[client]: request create error")
-		g.errch <- err
+		g.report(err)
 		return
 	}
 
+	start := g.clock.Now()
 	_, err = httpprovider.Request(req, g.httpClient)
+	elapsed := g.clock.Now().Sub(start).Seconds()
+
+	status := StatusPassing
+	if err != nil {
+		status = StatusCritical
+	}
+	tracing.End(span, err, attribute.String("goreg.health.status", string(status)))
+
+	g.metrics.checked(status, elapsed)
+	if g.history != nil {
+		status = g.history.Observe(service.Name, service.Hash, status)
+	}
+
+	previous, serr := g.store.SetStatus(service.Name, status)
+	if serr != nil {
+		g.logger.Warn("goreg->[server]: service {" + service.Name + "} was removed during check")
+	} else if previous != status {
+		before := service.clone()
+		before.Status = previous
+		after, _ := g.store.Get(service.Name)
+		g.record(systemCaller, AuditHealth, before, after)
+	}
+
+	if serr == nil {
+		g.evictCritical(service, status)
+	}
+
 	if err != nil {
 		g.logger.Error("goreg->[client]: request send error")
-		g.errch <- err
+		g.report(err)
+		return
+	}
+}
+
+// evictCritical deregisters service once it stayed critical for evictAfter.
+func (g *Server) evictCritical(service Service, status HealthStatus) {
+	if g.evictAfter == 0 {
+		return
+	}
+
+	g.criticalMu.Lock()
+	since, ok := g.critical[service.Name]
+	switch {
+	case status != StatusCritical:
+		delete(g.critical, service.Name)
+		g.criticalMu.Unlock()
+		return
+	case !ok || since.hash != service.Hash:
+		g.critical[service.Name] = criticalSince{hash: service.Hash, since: g.clock.Now()}
+		g.criticalMu.Unlock()
+		return
+	case g.clock.Now().Sub(since.since) < g.evictAfter:
+		g.criticalMu.Unlock()
+		return
+	}
+	delete(g.critical, service.Name)
+	g.criticalMu.Unlock()
+
+	removed, err := g.store.RemoveInstance(service.Name, service.Hash)
+	if err != nil {
+		return
+	}
+	g.metrics.deregistered()
+	g.logger.Warn("goreg->[server]: service {" + service.Name + "} was evicted after being critical for " + g.evictAfter.String())
+	g.record(systemCaller, AuditEvict, removed, nil)
+}
+
+// report hands err to the check loop, dropping it once the server is shut
+// down so probe goroutines never block.
+func (g *Server) report(err error) {
+	if !g.started.Load() {
+		g.logger.Error(err.Error())
 		return
 	}
+
+	select {
+	case g.errch <- err:
+	case <-g.closeCh:
+	}
 }
 
 func (g *Server) GetHandler(w http.ResponseWriter, r *http.Request) {
@@ -125,12 +555,25 @@ func (g *Server) GetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !g.authorize(w, r, name, CapabilityRead) {
+		return
+	}
+
+	if _, err := g.blockingQuery(w, r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	service, err := g.store.Get(name)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
+	setETag(w, service)
+	if notModified(w, r) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(service)
 }
@@ -153,14 +596,106 @@ func (g *Server) SetHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "name and callback are required", http.StatusBadRequest)
 		return
 	}
+	svc = advertised(svc, g.httpCaller(r))
 
-	if err := g.store.Set(svc.Name, svc.Callback); err != nil {
+	if err := validateService(svc); err != nil {
+		g.logger.Error("invalid service: " + err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !g.authorize(w, r, svc.Name, CapabilityRegister) {
+		return
+	}
+
+	cas, ifMatch, err := precondition(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cas != 0 || ifMatch {
+		g.updateService(w, r, svc, cas, ifMatch)
+		return
+	}
+
+	if err := g.store.SetService(svc); err != nil {
 		g.logger.Error("failed to set service: " + err.Error())
 		http.Error(w, "failed to set service: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	g.metrics.registered()
 
+	service, err := g.store.Get(svc.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g.record(g.httpCaller(r), AuditRegister, nil, service)
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(RegisterResponse{Name: service.Name, Hash: service.Hash})
+}
+
+// updateService is the compare-and-set branch of SetHandler: the existing
+// registration of svc is updated in place and keeps its hash.
+func (g *Server) updateService(w http.ResponseWriter, r *http.Request, svc Service, cas uint64, ifMatch bool) {
+	before, service, err := g.update(svc, cas)
+	switch {
+	case errors.Is(err, ErrServiceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrIndexMismatch) && ifMatch:
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return
+	case errors.Is(err, ErrIndexMismatch):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	g.record(g.httpCaller(r), AuditUpdate, before, service)
+
+	setETag(w, service)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RegisterResponse{Name: service.Name, Hash: service.Hash})
+}
+
+// update is ServerStore.Update with replaceWith(svc) that also returns the
+// registration as it was before the update.
+func (g *Server) update(svc Service, cas uint64) (before, after *Service, err error) {
+	after, err = g.store.Update(svc.Name, cas, func(s *Service) {
+		before = s.clone()
+		replaceWith(svc)(s)
+	})
+	return before, after, err
+}
+
+// replaceWith returns a ServerStore.Update function that copies the mutable fields
+// of svc.
+func replaceWith(svc Service) func(*Service) {
+	return func(s *Service) {
+		s.Callback = svc.Callback
+		s.Address = svc.Address
+		s.Port = svc.Port
+		s.Tags = svc.Tags
+		s.Metadata = svc.Metadata
+	}
+}
+
+func (g *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	if err := ValidateHttpMethod(r.Method, http.MethodGet); err != nil {
+		http.Error(w, err.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{
+		Status:   "ok",
+		Services: len(g.store.GetAll()),
+	})
 }
 
 func (g *Server) GetAllHandler(w http.ResponseWriter, r *http.Request) {
@@ -169,11 +704,84 @@ func (g *Server) GetAllHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	services := g.store.GetAll()
+	index, err := g.blockingQuery(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	services := g.readable(r, g.store.GetAll())
+
+	g.setListETag(w, index)
+	if notModified(w, r) {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(services)
 }
 
+// readable drops the services the request token may not read.
+func (g *Server) readable(r *http.Request, services []*Service) []*Service {
+	if g.acl == nil {
+		return services
+	}
+
+	token := RequestToken(r)
+	readable := make([]*Service, 0, len(services))
+	for _, service := range services {
+		if g.acl.Authorize(token, service.Name, CapabilityRead) {
+			readable = append(readable, service)
+		}
+	}
+	return readable
+}
+
+func validateService(svc Service) error {
+	var port error
+	if svc.Port != 0 {
+		port = validation.Port("port", svc.Port)
+	}
+
+	return errors.Join(
+		validation.ServiceName("name", svc.Name),
+		validation.URL("callback", svc.Callback),
+		port,
+	)
+}
+
+// advertised fills in the source address of c for clients that could not
+// tell their own address: a callback host, or an address, that is unspecified
+// (0.0.0.0, ::) or missing.
+func advertised(svc Service, c caller) Service {
+	if c.sourceIP == "" {
+		return svc
+	}
+
+	if callback, err := url.Parse(svc.Callback); err == nil && callback.Host != "" && unspecifiedHost(callback.Hostname()) {
+		if port := callback.Port(); port != "" {
+			callback.Host = net.JoinHostPort(c.sourceIP, port)
+		} else if strings.Contains(c.sourceIP, ":") {
+			callback.Host = "[" + c.sourceIP + "]"
+		} else {
+			callback.Host = c.sourceIP
+		}
+		svc.Callback = callback.String()
+	}
+
+	if svc.Address != "" && unspecifiedHost(svc.Address) {
+		svc.Address = c.sourceIP
+	}
+	return svc
+}
+
+func unspecifiedHost(host string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
 func (g *Server) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	if err := ValidateHttpMethod(r.Method, http.MethodDelete); err != nil {
 		http.Error(w, err.Error(), http.StatusMethodNotAllowed)
@@ -186,14 +794,86 @@ func (g *Server) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := g.store.Delete(name); err != nil {
+	if !g.authorize(w, r, name, CapabilityDeregister) {
+		return
+	}
+
+	removed, err := g.store.Remove(name)
+	if err != nil {
 		http.Error(w, "Failed to delete service", http.StatusInternalServerError)
 		return
 	}
+	g.metrics.deregistered()
+	g.record(g.httpCaller(r), AuditDeregister, removed, nil)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (g *Server) PoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	if g.acl == nil {
+		http.Error(w, "acl is disabled", http.StatusNotFound)
+		return
+	}
+
+	if !g.authorize(w, r, "*", CapabilityAdmin) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.acl.Policies())
+	case http.MethodPut, http.MethodPost:
+		var policy ACLPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "invalid input", http.StatusBadRequest)
+			return
+		}
+
+		if err := g.acl.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		g.logger.Info("goreg->[server]: acl policy {" + policy.Name + "} was set")
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := g.acl.DeletePolicy(name); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		g.logger.Info("goreg->[server]: acl policy {" + name + "} was removed")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (g *Server) authorize(w http.ResponseWriter, r *http.Request, service string, capability Capability) bool {
+	if g.allowed(r, service, capability) {
+		return true
+	}
+
+	http.Error(w, "permission denied", http.StatusForbidden)
+	return false
+}
+
+func (g *Server) allowed(r *http.Request, service string, capability Capability) bool {
+	if g.acl == nil || g.acl.Authorize(RequestToken(r), service, capability) {
+		return true
+	}
+
+	g.logger.Warn("goreg->[server]: permission denied: " + string(capability) + " on {" + service + "}")
+	return false
+}
+
 func ValidateHttpMethod(method string, requiredMethod string) error {
 	if method != requiredMethod {
 		return errors.New("method not allowed")
@@ -2,12 +2,100 @@ package server
 
 import (
 	"errors"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/tlsprovider"
+	"github.com/Danis0n/goreg/internal/goreg/validation"
 )
 
 type ServerConfig struct {
-	Port int `yaml:"port"`
+	Port int                `yaml:"port" json:"port"`
+	ACL  ACLConfig          `yaml:"acl" json:"acl"`
+	TLS  tlsprovider.Config `yaml:"tls" json:"tls"`
+	// CheckInterval is the pause between two rounds of callback probes.
+	CheckInterval time.Duration `yaml:"check_interval" json:"check_interval"`
+	// EvictAfter deregisters services that stayed critical for that long.
+	// Zero keeps critical services until they deregister.
+	EvictAfter time.Duration `yaml:"evict_after" json:"evict_after"`
+	DNS        DNSConfig     `yaml:"dns" json:"dns"`
+	GRPC       GRPCConfig    `yaml:"grpc" json:"grpc"`
+	Audit      AuditConfig   `yaml:"audit" json:"audit"`
+	History    HistoryConfig `yaml:"history" json:"history"`
+	UI         UIConfig      `yaml:"ui" json:"ui"`
+}
+
+// UIConfig enables the web dashboard served under /ui/.
+type UIConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+}
+
+// HistoryConfig bounds the transition history kept per instance and tunes
+// flap detection: an instance flaps when its health changed FlapThreshold
+// times within FlapWindow.
+type HistoryConfig struct {
+	// Size is the number of transitions kept per instance, DefaultHistorySize
+	// when zero.
+	Size int `yaml:"size" json:"size"`
+	// FlapWindow defaults to DefaultFlapWindow.
+	FlapWindow time.Duration `yaml:"flap_window" json:"flap_window"`
+	// FlapThreshold defaults to DefaultFlapThreshold.
+	FlapThreshold int `yaml:"flap_threshold" json:"flap_threshold"`
+}
+
+// AuditConfig controls the audit trail of registry mutations.
+type AuditConfig struct {
+	// Path of a JSON lines file every entry is appended to. Entries are only
+	// kept in memory when empty.
+	Path string `yaml:"path" json:"path"`
+	// Capacity is the number of entries kept in memory for queries,
+	// DefaultAuditCapacity when zero.
+	Capacity int `yaml:"capacity" json:"capacity"`
+}
+
+// GRPCConfig enables the gRPC registry API on its own port.
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Port defaults to DefaultGRPCPort.
+	Port int `yaml:"port" json:"port"`
+}
+
+// DNSConfig enables the embedded DNS interface answering
+// <name>.service.goreg. queries.
+type DNSConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Address is the UDP address to listen on, DefaultDNSAddress when empty.
+	Address string `yaml:"address" json:"address"`
+	// TTL of the records served, DefaultDNSTTL when zero.
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+	// AllowUnscoped must be set to serve DNS with the ACL enabled: DNS
+	// answers every passing service, whatever the read rules.
+	AllowUnscoped bool `yaml:"allow_unscoped" json:"allow_unscoped"`
 }
 
+const (
+	DefaultCheckInterval = time.Minute
+	minCheckInterval     = time.Second
+	maxCheckInterval     = time.Hour
+
+	DefaultDNSAddress = ":8600"
+	DefaultDNSTTL     = 30 * time.Second
+	minDNSTTL         = time.Second
+	maxDNSTTL         = 24 * time.Hour
+
+	DefaultGRPCPort = 8502
+
+	minEvictAfter = time.Second
+	maxEvictAfter = 30 * 24 * time.Hour
+
+	DefaultAuditCapacity = 10000
+
+	DefaultHistorySize   = 32
+	DefaultFlapWindow    = 10 * time.Minute
+	DefaultFlapThreshold = 5
+	minFlapWindow        = time.Second
+	maxFlapWindow        = 24 * time.Hour
+)
+
 func NewServerConfig(port int) (ServerConfig, error) {
 	if err := validateServerSettings(port); err != nil {
 		return ServerConfig{}, err
@@ -19,16 +107,73 @@ func NewServerConfig(port int) (ServerConfig, error) {
 }
 
 func ValidateServerConfig(cfg ServerConfig) error {
-	if err := validateServerSettings(cfg.Port); err != nil {
-		return err
-	}
-	return nil
+	return errors.Join(
+		validateServerSettings(cfg.Port),
+		validation.Duration("check_interval", cfg.CheckInterval, minCheckInterval, maxCheckInterval),
+		validation.Duration("evict_after", cfg.EvictAfter, minEvictAfter, maxEvictAfter),
+		validation.Duration("dns.ttl", cfg.DNS.TTL, minDNSTTL, maxDNSTTL),
+		validateDNSConfig(cfg.DNS, cfg.ACL),
+		validateAuditConfig(cfg.Audit),
+		validateHistoryConfig(cfg.History),
+		validateGRPCConfig(cfg.GRPC),
+		ValidateACLConfig(cfg.ACL),
+		tlsprovider.ValidateConfig(cfg.TLS),
+	)
 }
 
 func validateServerSettings(port int) error {
-	if port <= 0 {
-		return errors.New("port invalid")
+	return validation.Port("port", port)
+}
+
+func validateGRPCConfig(cfg GRPCConfig) error {
+	if cfg.Port == 0 {
+		return nil
+	}
+	return validation.Port("grpc.port", cfg.Port)
+}
+
+func validateDNSConfig(cfg DNSConfig, acl ACLConfig) error {
+	if !cfg.Enabled || !acl.Enabled || cfg.AllowUnscoped {
+		return nil
+	}
+	return &validation.FieldError{
+		Field:  "dns.allow_unscoped",
+		Value:  cfg.AllowUnscoped,
+		Err:    validation.ErrRequired,
+		Detail: "dns answers are not scoped by acl read rules",
 	}
+}
 
+func validateAuditConfig(cfg AuditConfig) error {
+	if cfg.Capacity < 0 {
+		return &validation.FieldError{
+			Field:  "audit.capacity",
+			Value:  cfg.Capacity,
+			Err:    validation.ErrOutOfRange,
+			Detail: "must not be negative",
+		}
+	}
 	return nil
 }
+
+func validateHistoryConfig(cfg HistoryConfig) error {
+	var errs []error
+	if cfg.Size < 0 {
+		errs = append(errs, &validation.FieldError{
+			Field:  "history.size",
+			Value:  cfg.Size,
+			Err:    validation.ErrOutOfRange,
+			Detail: "must not be negative",
+		})
+	}
+	if cfg.FlapThreshold < 0 || cfg.FlapThreshold == 1 {
+		errs = append(errs, &validation.FieldError{
+			Field:  "history.flap_threshold",
+			Value:  cfg.FlapThreshold,
+			Err:    validation.ErrOutOfRange,
+			Detail: "must be at least 2",
+		})
+	}
+	errs = append(errs, validation.Duration("history.flap_window", cfg.FlapWindow, minFlapWindow, maxFlapWindow))
+	return errors.Join(errs...)
+}
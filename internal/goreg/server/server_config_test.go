@@ -2,6 +2,7 @@ package server
 
 import (
 	"testing"
+	"time"
 )
 
 // TestNewServerConfig проверяет создание ServerConfig с различными значениями портов.
@@ -63,6 +64,41 @@ func TestValidateServerConfig(t *testing.T) {
 			cfg:       ServerConfig{Port: 0},
 			wantError: true,
 		},
+		{
+			name:      "Invalid config (check interval)",
+			cfg:       ServerConfig{Port: 8080, CheckInterval: time.Millisecond},
+			wantError: true,
+		},
+		{
+			name:      "Invalid config (dns ttl)",
+			cfg:       ServerConfig{Port: 8080, DNS: DNSConfig{Enabled: true, TTL: 48 * time.Hour}},
+			wantError: true,
+		},
+		{
+			name:      "Invalid config (dns with acl)",
+			cfg:       ServerConfig{Port: 8080, DNS: DNSConfig{Enabled: true}, ACL: ACLConfig{Enabled: true}},
+			wantError: true,
+		},
+		{
+			name:      "Valid config (unscoped dns with acl)",
+			cfg:       ServerConfig{Port: 8080, DNS: DNSConfig{Enabled: true, AllowUnscoped: true}, ACL: ACLConfig{Enabled: true}},
+			wantError: false,
+		},
+		{
+			name:      "Invalid config (evict after)",
+			cfg:       ServerConfig{Port: 8080, EvictAfter: time.Millisecond},
+			wantError: true,
+		},
+		{
+			name:      "Invalid config (audit capacity)",
+			cfg:       ServerConfig{Port: 8080, Audit: AuditConfig{Capacity: -1}},
+			wantError: true,
+		},
+		{
+			name:      "Invalid config (flap threshold)",
+			cfg:       ServerConfig{Port: 8080, History: HistoryConfig{FlapThreshold: 1}},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -97,6 +133,11 @@ func TestValidateServerSettings(t *testing.T) {
 			port:      0,
 			wantError: true,
 		},
+		{
+			name:      "Invalid port (out of range)",
+			port:      99999,
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+	"github.com/Danis0n/goreg/internal/goreg/httpprovider"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type Option func(*options)
+
+type options struct {
+	logger     *zap.Logger
+	httpClient httpprovider.HttpClient
+	clock      clock.Clock
+	store      *ServerStore
+	listener   net.Listener
+	dnsConn    net.PacketConn
+
+	grpcListener net.Listener
+
+	tracerProvider trace.TracerProvider
+}
+
+// WithLogger replaces the development logger created by NewServer.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithHTTPClient sets the client used to probe service callbacks. It takes
+// precedence over the one built from ServerConfig.TLS.
+func WithHTTPClient(client httpprovider.HttpClient) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithStore makes the server serve an existing store instead of a new one.
+func WithStore(store *ServerStore) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithListener makes Start serve on l instead of listening on ServerConfig.Port.
+func WithListener(l net.Listener) Option {
+	return func(o *options) {
+		o.listener = l
+	}
+}
+
+// WithDNSPacketConn makes the DNS interface serve on pc instead of listening
+// on DNSConfig.Address. It has no effect unless DNS is enabled.
+func WithDNSPacketConn(pc net.PacketConn) Option {
+	return func(o *options) {
+		o.dnsConn = pc
+	}
+}
+
+// WithGRPCListener makes the gRPC API serve on l instead of listening on
+// GRPCConfig.Port. It has no effect unless gRPC is enabled.
+func WithGRPCListener(l net.Listener) Option {
+	return func(o *options) {
+		o.grpcListener = l
+	}
+}
+
+// WithTracerProvider enables OpenTelemetry spans for handlers and health
+// checks. Tracing is a no-op without it.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.logger == nil {
+		logger, err := zap.NewDevelopment()
+		if err != nil {
+			return nil, err
+		}
+		o.logger = logger
+	}
+
+	if o.clock == nil {
+		o.clock = clock.Real()
+	}
+
+	return o, nil
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Danis0n/goreg/internal/goreg/clock"
+	"go.uber.org/zap"
+)
+
+func TestNewServer_Options(t *testing.T) {
+	logger := zap.NewNop()
+	store, _ := NewServerStore(logger)
+	c := clock.NewManual(time.Now())
+	httpClient := &http.Client{}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	server, err := NewServer(
+		ServerConfig{Port: 8080},
+		WithLogger(logger),
+		WithStore(store),
+		WithClock(c),
+		WithHTTPClient(httpClient),
+		WithListener(ln),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if server.logger != logger {
+		t.Errorf("expected logger option to be used")
+	}
+
+	if server.store != store {
+		t.Errorf("expected store option to be used")
+	}
+
+	if server.clock != c {
+		t.Errorf("expected clock option to be used")
+	}
+
+	if server.httpClient != httpClient {
+		t.Errorf("expected http client option to be used")
+	}
+
+	if server.listener != ln {
+		t.Errorf("expected listener option to be used")
+	}
+}
+
+func TestNewServer_DefaultHTTPClient(t *testing.T) {
+	server, err := NewServer(ServerConfig{Port: 8080}, WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if server.httpClient == nil {
+		t.Fatal("expected default http client to be set")
+	}
+}
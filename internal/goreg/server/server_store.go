@@ -2,22 +2,87 @@ package server
 
 import (
 	"errors"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
 	"sync"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+var (
+	ErrServiceNotFound = errors.New("registrator [server]: service not found")
+	ErrServiceExists   = errors.New("registrator [server]: server already exists")
+	ErrIndexMismatch   = errors.New("registrator [server]: modify index mismatch")
+	ErrBatchAborted    = errors.New("registrator [server]: batch rolled back")
+	ErrUnknownBatchOp  = errors.New("registrator [server]: unknown batch operation")
+)
+
+type HealthStatus string
+
+const (
+	StatusPassing  HealthStatus = "passing"
+	StatusCritical HealthStatus = "critical"
+)
+
 type Service struct {
-	Name     string
-	Hash     string
-	Callback string
+	Name     string            `json:"name" yaml:"name"`
+	Hash     string            `json:"hash" yaml:"hash"`
+	Callback string            `json:"callback" yaml:"callback"`
+	Status   HealthStatus      `json:"status" yaml:"status"`
+	Address  string            `json:"address,omitempty" yaml:"address,omitempty"`
+	Port     int               `json:"port,omitempty" yaml:"port,omitempty"`
+	Tags     []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	// CreateIndex and ModifyIndex are store indexes of the registration and
+	// of its last change, used as compare-and-set tokens.
+	CreateIndex uint64 `json:"create_index,omitempty" yaml:"create_index,omitempty"`
+	ModifyIndex uint64 `json:"modify_index,omitempty" yaml:"modify_index,omitempty"`
+}
+
+func (s *Service) clone() *Service {
+	copied := *s
+	copied.Tags = slices.Clone(s.Tags)
+	copied.Metadata = maps.Clone(s.Metadata)
+	return &copied
+}
+
+// Target is the host:port the service is reached at: the registered address
+// and port, each falling back to the callback URL.
+func (s *Service) Target() (string, error) {
+	callback, err := url.Parse(s.Callback)
+	if err != nil {
+		return "", err
+	}
+
+	host := s.Address
+	if host == "" {
+		host = callback.Hostname()
+	}
+
+	port := callback.Port()
+	if s.Port != 0 {
+		port = strconv.Itoa(s.Port)
+	}
+	if port == "" {
+		port = "80"
+		if callback.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return net.JoinHostPort(host, port), nil
 }
 
 type ServerStore struct {
 	logger   *zap.Logger
 	rwmu     *sync.RWMutex
 	services map[string]*Service
+	changed  chan struct{}
+	index    uint64
 }
 
 func NewServerStore(logger *zap.Logger) (*ServerStore, error) {
@@ -29,6 +94,7 @@ func NewServerStore(logger *zap.Logger) (*ServerStore, error) {
 		logger:   logger,
 		rwmu:     &sync.RWMutex{},
 		services: make(map[string]*Service),
+		changed:  make(chan struct{}),
 	}, nil
 }
 
@@ -36,29 +102,35 @@ func (g *ServerStore) Get(name string) (*Service, error) {
 	g.rwmu.RLock()
 	defer g.rwmu.RUnlock()
 
-	services, ok := g.services[name]
+	service, ok := g.services[name]
 	if !ok {
-		return nil, errors.New("registrator [server]: service not found")
+		return nil, ErrServiceNotFound
 	}
 
-	return services, nil
+	return service.clone(), nil
 }
 
 func (g *ServerStore) Set(name string, callback string) error {
+	return g.SetService(Service{Name: name, Callback: callback})
+}
+
+// SetService registers svc with a fresh hash and a passing status. Hash and
+// Status set by the caller are ignored.
+func (g *ServerStore) SetService(svc Service) error {
 	g.rwmu.Lock()
 	defer g.rwmu.Unlock()
 
-	_, ok := g.services[name]
+	_, ok := g.services[svc.Name]
 	if ok {
-		return errors.New("registrator [server]: server already exists")
+		return ErrServiceExists
 	}
 
-	g.services[name] = &Service{
-		Name:     name,
-		Hash:     uuid.New().String(),
-		Callback: callback,
-	}
-	g.logger.Info("Registrator [server]: service: " + name + " was registered")
+	svc.Hash = uuid.New().String()
+	svc.Status = StatusPassing
+	svc.CreateIndex = g.bump()
+	svc.ModifyIndex = svc.CreateIndex
+	g.services[svc.Name] = svc.clone()
+	g.logger.Info("Registrator [server]: service: " + svc.Name + " was registered")
 
 	return nil
 }
@@ -69,23 +141,206 @@ func (g *ServerStore) GetAll() []*Service {
 
 	servers := make([]*Service, 0, len(g.services))
 	for _, value := range g.services {
-		servers = append(servers, value)
+		servers = append(servers, value.clone())
 	}
 
 	return servers
 }
 
 func (g *ServerStore) Delete(key string) error {
+	_, err := g.Remove(key)
+	return err
+}
+
+// Remove deletes the registration of key and returns it.
+func (g *ServerStore) Remove(key string) (*Service, error) {
+	return g.RemoveInstance(key, "")
+}
+
+// RemoveInstance deletes the registration of key only while it carries hash,
+// so a newer registration of the same name is kept. An empty hash matches any.
+func (g *ServerStore) RemoveInstance(key, hash string) (*Service, error) {
 	g.rwmu.Lock()
 	defer g.rwmu.Unlock()
 
-	_, ok := g.services[key]
-	if !ok {
-		return errors.New("Registrator [server]: key{" + key + "} doesn't exists")
+	service, ok := g.services[key]
+	if !ok || (hash != "" && service.Hash != hash) {
+		return nil, ErrServiceNotFound
 	}
 
 	delete(g.services, key)
+	g.bump()
 	g.logger.Info("Registrator [server]: service: {" + key + "} was removed")
 
-	return nil
+	return service.clone(), nil
+}
+
+// SetStatus records the result of a health check. It returns the previous
+// status so callers can tell transitions from repeated results.
+func (g *ServerStore) SetStatus(name string, status HealthStatus) (HealthStatus, error) {
+	g.rwmu.Lock()
+	defer g.rwmu.Unlock()
+
+	service, ok := g.services[name]
+	if !ok {
+		return "", ErrServiceNotFound
+	}
+
+	previous := service.Status
+	service.Status = status
+	if previous != status {
+		service.ModifyIndex = g.bump()
+		g.logger.Info("Registrator [server]: service: {" + name + "} is " + string(status))
+	}
+
+	return previous, nil
+}
+
+// Changed returns a channel that is closed on the next change of the store
+// content. Callers take a new channel after every wake-up.
+func (g *ServerStore) Changed() <-chan struct{} {
+	g.rwmu.RLock()
+	defer g.rwmu.RUnlock()
+	return g.changed
+}
+
+// Index is the store index of the last change.
+func (g *ServerStore) Index() uint64 {
+	g.rwmu.RLock()
+	defer g.rwmu.RUnlock()
+	return g.index
+}
+
+// Update applies fn to the registration of name. A non-zero cas must equal
+// its ModifyIndex, otherwise ErrIndexMismatch is returned. Only the callback,
+// address, port, tags and metadata set by fn are kept.
+func (g *ServerStore) Update(name string, cas uint64, fn func(*Service)) (*Service, error) {
+	g.rwmu.Lock()
+	defer g.rwmu.Unlock()
+
+	service, ok := g.services[name]
+	if !ok {
+		return nil, ErrServiceNotFound
+	}
+
+	if cas != 0 && cas != service.ModifyIndex {
+		return nil, ErrIndexMismatch
+	}
+
+	updated := service.clone()
+	fn(updated)
+
+	service.Callback = updated.Callback
+	service.Address = updated.Address
+	service.Port = updated.Port
+	service.Tags = slices.Clone(updated.Tags)
+	service.Metadata = maps.Clone(updated.Metadata)
+	service.ModifyIndex = g.bump()
+	g.logger.Info("Registrator [server]: service: {" + name + "} was updated")
+
+	return service.clone(), nil
+}
+
+type BatchVerb string
+
+const (
+	BatchRegister   BatchVerb = "register"
+	BatchDeregister BatchVerb = "deregister"
+	BatchRenew      BatchVerb = "renew"
+)
+
+// BatchOp is one operation of ServerStore.Apply. Register takes the whole
+// service; deregister and renew only its name and hash, which renew requires
+// and deregister checks when set.
+type BatchOp struct {
+	Verb    BatchVerb `json:"verb"`
+	Service Service   `json:"service"`
+}
+
+// BatchResult is the outcome of one BatchOp: the registration after a
+// register or renew, the removed one after a deregister.
+type BatchResult struct {
+	Service *Service
+	Err     error
+}
+
+// Apply runs ops in order as one transaction. Either every operation is
+// applied under a single store index, which is returned, or none is and
+// ErrBatchAborted is returned; the operations that did not fail themselves
+// then carry ErrBatchAborted as well.
+func (g *ServerStore) Apply(ops []BatchOp) ([]BatchResult, uint64, error) {
+	g.rwmu.Lock()
+	defer g.rwmu.Unlock()
+
+	staged := maps.Clone(g.services)
+	index := g.index + 1
+	results := make([]BatchResult, len(ops))
+	failed, changed := false, false
+	for i, op := range ops {
+		results[i] = stage(staged, op, index)
+		switch {
+		case results[i].Err != nil:
+			failed = true
+		case op.Verb != BatchRenew:
+			changed = true
+		}
+	}
+
+	if failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = BatchResult{Err: ErrBatchAborted}
+			}
+		}
+		return results, 0, ErrBatchAborted
+	}
+
+	if changed {
+		g.services = staged
+		g.bump()
+		g.logger.Info("Registrator [server]: batch of " + strconv.Itoa(len(ops)) + " operations was applied")
+	}
+
+	return results, g.index, nil
+}
+
+// stage applies op to services, which the registrations are shared with, so
+// it replaces entries instead of changing them.
+func stage(services map[string]*Service, op BatchOp, index uint64) BatchResult {
+	svc := op.Service
+	if op.Verb == BatchRegister {
+		if _, ok := services[svc.Name]; ok {
+			return BatchResult{Err: ErrServiceExists}
+		}
+
+		svc.Hash = uuid.New().String()
+		svc.Status = StatusPassing
+		svc.CreateIndex = index
+		svc.ModifyIndex = index
+		services[svc.Name] = svc.clone()
+		return BatchResult{Service: svc.clone()}
+	}
+
+	service, ok := services[svc.Name]
+	if !ok || (svc.Hash != "" && svc.Hash != service.Hash) {
+		return BatchResult{Err: ErrServiceNotFound}
+	}
+
+	switch op.Verb {
+	case BatchDeregister:
+		delete(services, svc.Name)
+	case BatchRenew:
+	default:
+		return BatchResult{Err: ErrUnknownBatchOp}
+	}
+	return BatchResult{Service: service.clone()}
+}
+
+// bump advances the store index and wakes up the waiters of Changed. Callers
+// hold the write lock.
+func (g *ServerStore) bump() uint64 {
+	g.index++
+	close(g.changed)
+	g.changed = make(chan struct{})
+	return g.index
 }
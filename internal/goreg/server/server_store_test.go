@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"testing"
 
 	"go.uber.org/zap"
@@ -127,3 +128,196 @@ func TestServerStore_Delete(t *testing.T) {
 		t.Fatalf("expected error on Delete for non-existent service, got nil")
 	}
 }
+
+func TestServerStore_RemoveInstance(t *testing.T) {
+	store, _ := NewServerStore(getTestLogger())
+	store.Set("orders", "http://callback.url")
+	old, _ := store.Get("orders")
+	store.Delete("orders")
+	store.Set("orders", "http://callback.url")
+	current, _ := store.Get("orders")
+
+	if _, err := store.RemoveInstance("orders", old.Hash); !errors.Is(err, ErrServiceNotFound) {
+		t.Fatalf("expected ErrServiceNotFound for a stale hash, got %v", err)
+	}
+
+	if _, err := store.Get("orders"); err != nil {
+		t.Fatalf("expected newer registration to be kept, got %v", err)
+	}
+
+	removed, err := store.RemoveInstance("orders", current.Hash)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if removed.Hash != current.Hash {
+		t.Errorf("expected removed hash %v, got %v", current.Hash, removed.Hash)
+	}
+}
+
+func TestServerStore_SetStatus(t *testing.T) {
+	logger := getTestLogger()
+	store, _ := NewServerStore(logger)
+	store.Set("testService", "http://callback.url")
+
+	service, _ := store.Get("testService")
+	if service.Status != StatusPassing {
+		t.Fatalf("expected new service to be %v, got %v", StatusPassing, service.Status)
+	}
+
+	previous, err := store.SetStatus("testService", StatusCritical)
+	if err != nil {
+		t.Fatalf("expected no error on SetStatus, got %v", err)
+	}
+
+	if previous != StatusPassing {
+		t.Fatalf("expected previous status %v, got %v", StatusPassing, previous)
+	}
+
+	service, _ = store.Get("testService")
+	if service.Status != StatusCritical {
+		t.Fatalf("expected status %v, got %v", StatusCritical, service.Status)
+	}
+
+	if _, err := store.SetStatus("nonExistentService", StatusPassing); err == nil {
+		t.Fatalf("expected error on SetStatus for non-existent service, got nil")
+	}
+}
+
+func TestServerStore_Changed(t *testing.T) {
+	store, _ := NewServerStore(zap.NewNop())
+
+	changed := store.Changed()
+	select {
+	case <-changed:
+		t.Fatal("expected no change before the first write")
+	default:
+	}
+
+	store.Set("testService", "http://callback.url")
+
+	select {
+	case <-changed:
+	default:
+		t.Fatal("expected change after Set")
+	}
+
+	changed = store.Changed()
+	store.SetStatus("testService", StatusPassing)
+	select {
+	case <-changed:
+		t.Fatal("expected no change when the status is unchanged")
+	default:
+	}
+}
+
+func TestServerStore_Update(t *testing.T) {
+	store, _ := NewServerStore(zap.NewNop())
+	store.Set("testService", "http://callback.url")
+
+	before, _ := store.Get("testService")
+	if before.CreateIndex != 1 || before.ModifyIndex != 1 {
+		t.Fatalf("unexpected indexes %d/%d", before.CreateIndex, before.ModifyIndex)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		cas  uint64
+		err  error
+	}{
+		{"matching cas", "testService", 1, nil},
+		{"stale cas", "testService", 1, ErrIndexMismatch},
+		{"unconditional", "testService", 0, nil},
+		{"unknown", "other", 0, ErrServiceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Update(tt.key, tt.cas, func(s *Service) {
+				s.Metadata = map[string]string{"version": tt.name}
+				s.Hash = "ignored"
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: got %v want %v", err, tt.err)
+			}
+		})
+	}
+
+	after, _ := store.Get("testService")
+	if after.Hash != before.Hash || after.Metadata["version"] != "unconditional" || after.ModifyIndex != 3 || store.Index() != 3 {
+		t.Errorf("unexpected service after updates: %+v", after)
+	}
+}
+
+func TestServerStore_Apply(t *testing.T) {
+	store, _ := NewServerStore(zap.NewNop())
+	store.Set("orders", "http://orders:8080/callback")
+	orders, _ := store.Get("orders")
+
+	register := func(name string) BatchOp {
+		return BatchOp{Verb: BatchRegister, Service: Service{Name: name, Callback: "http://" + name + ":8080/callback"}}
+	}
+
+	tests := []struct {
+		name  string
+		ops   []BatchOp
+		errs  []error
+		index uint64
+	}{
+		{
+			name:  "applied",
+			ops:   []BatchOp{register("billing"), register("users"), {Verb: BatchRenew, Service: Service{Name: "orders", Hash: orders.Hash}}},
+			errs:  []error{nil, nil, nil},
+			index: 2,
+		},
+		{
+			name:  "rolled back",
+			ops:   []BatchOp{register("payments"), register("billing"), {Verb: BatchDeregister, Service: Service{Name: "orders"}}},
+			errs:  []error{ErrBatchAborted, ErrServiceExists, ErrBatchAborted},
+			index: 2,
+		},
+		{
+			name:  "stale hash",
+			ops:   []BatchOp{{Verb: BatchDeregister, Service: Service{Name: "orders", Hash: "stale"}}},
+			errs:  []error{ErrServiceNotFound},
+			index: 2,
+		},
+		{
+			name:  "register after deregister",
+			ops:   []BatchOp{{Verb: BatchDeregister, Service: Service{Name: "users"}}, register("users")},
+			errs:  []error{nil, nil},
+			index: 3,
+		},
+		{
+			name:  "renew only",
+			ops:   []BatchOp{{Verb: BatchRenew, Service: Service{Name: "orders", Hash: orders.Hash}}},
+			errs:  []error{nil},
+			index: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, _, _ := store.Apply(tt.ops)
+			for i, result := range results {
+				if !errors.Is(result.Err, tt.errs[i]) {
+					t.Errorf("operation %d: got %v want %v", i, result.Err, tt.errs[i])
+				}
+			}
+			if store.Index() != tt.index {
+				t.Errorf("expected index %d, got %d", tt.index, store.Index())
+			}
+		})
+	}
+
+	if _, err := store.Get("payments"); err == nil {
+		t.Error("rolled back registration is in the store")
+	}
+	if _, err := store.Get("orders"); err != nil {
+		t.Error("rolled back deregistration removed orders")
+	}
+	if users, _ := store.Get("users"); users.CreateIndex != 3 {
+		t.Errorf("re-registered users has create index %d", users.CreateIndex)
+	}
+}
@@ -2,10 +2,15 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -73,6 +78,46 @@ func TestGetHandler(t *testing.T) {
 		t.Errorf("handler returned unexpected body: got %v want %v",
 			service.Name, "testService")
 	}
+
+	if etag := rr.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("handler returned unexpected etag: got %v want %v", etag, `"1"`)
+	}
+}
+
+func TestSetHandler_CAS(t *testing.T) {
+	server := setupTestServer()
+	server.store.Set("testService", "http://callback.url")
+
+	svc := Service{Name: "testService", Callback: "http://other.url"}
+	body, _ := json.Marshal(svc)
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"matching cas", "/set?cas=1", http.StatusOK},
+		{"stale cas", "/set?cas=1", http.StatusConflict},
+		{"invalid cas", "/set?cas=abc", http.StatusBadRequest},
+		{"no cas", "/set", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, bytes.NewReader(body))
+			rr := httptest.NewRecorder()
+			server.SetHandler(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.status)
+			}
+		})
+	}
+
+	service, _ := server.store.Get("testService")
+	if service.Callback != svc.Callback || service.ModifyIndex != 2 {
+		t.Errorf("unexpected service after updates: %+v", service)
+	}
 }
 
 func TestGetAllHandler(t *testing.T) {
@@ -149,3 +194,182 @@ func TestInvalidMethod(t *testing.T) {
 			status, http.StatusMethodNotAllowed)
 	}
 }
+
+func TestServer_ListenAndServeShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer(ServerConfig{Port: 8080}, WithLogger(zap.NewNop()), WithListener(ln))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	var res *http.Response
+	for i := 0; i < 50; i++ {
+		if res, err = http.Get("http://" + ln.Addr().String() + "/health"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("expected server to answer, got %v", err)
+	}
+	res.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("expected clean shutdown, got %v", err)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Errorf("expected ListenAndServe to return nil after shutdown, got %v", err)
+	}
+}
+
+func TestServer_ShutdownBeforeServe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer(ServerConfig{Port: 8080}, WithLogger(zap.NewNop()), WithListener(ln))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected clean shutdown, got %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("expected ListenAndServe to return nil after shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected ListenAndServe to return at once after shutdown")
+	}
+
+	if err := server.Start(); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("expected Start to return http.ErrServerClosed after shutdown, got %v", err)
+	}
+}
+
+func TestServer_ConcurrentShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer(ServerConfig{Port: 8080}, WithLogger(zap.NewNop()), WithListener(ln))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("expected clean shutdown, got %v", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("expected ListenAndServe to return nil after shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected ListenAndServe to return after a concurrent shutdown")
+	}
+}
+
+func TestServer_ShutdownTimeoutClosesAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	server, err := NewServer(ServerConfig{Port: 8080, Audit: AuditConfig{Path: path}}, WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A check loop that never finishes draining.
+	server.started.Store(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := server.Shutdown(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if server.audit.file != nil {
+		t.Error("expected the audit file to be closed after a timed out shutdown")
+	}
+}
+
+func TestServer_ListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	server, err := NewServer(ServerConfig{Port: port}, WithLogger(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.Start(); err == nil {
+		t.Fatal("expected listen error for a busy port, got nil")
+	}
+}
+
+func TestAdvertised(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      Service
+		sourceIP string
+		callback string
+		address  string
+	}{
+		{"unspecified ipv4", Service{Callback: "http://0.0.0.0:8080/callback"}, "10.0.0.7", "http://10.0.0.7:8080/callback", ""},
+		{"unspecified ipv6", Service{Callback: "http://[::]:8080/callback"}, "fd00::7", "http://[fd00::7]:8080/callback", ""},
+		{"no port", Service{Callback: "https://0.0.0.0/callback"}, "fd00::7", "https://[fd00::7]/callback", ""},
+		{"unspecified address", Service{Callback: "http://orders:8080/callback", Address: "0.0.0.0"}, "10.0.0.7", "http://orders:8080/callback", "10.0.0.7"},
+		{"advertised", Service{Callback: "http://10.0.0.1:8080/callback", Address: "10.0.0.2"}, "10.0.0.7", "http://10.0.0.1:8080/callback", "10.0.0.2"},
+		{"unknown source", Service{Callback: "http://0.0.0.0:8080/callback"}, "", "http://0.0.0.0:8080/callback", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := advertised(tt.svc, caller{actor: ActorHTTP, sourceIP: tt.sourceIP})
+			if got.Callback != tt.callback || got.Address != tt.address {
+				t.Errorf("got callback %q address %q, want %q %q", got.Callback, got.Address, tt.callback, tt.address)
+			}
+		})
+	}
+
+	server := setupTestServer()
+	serveV1(t, server.Handler(), http.MethodPost, "/v1/services", Service{Name: "orders", Callback: "http://0.0.0.0:8080/callback"})
+	if orders, err := server.store.Get("orders"); err != nil || orders.Callback != "http://192.0.2.1:8080/callback" {
+		t.Errorf("registration did not fall back to the remote address: %+v", orders)
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestTracing_HealthCheckPropagatesContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	var traceparent string
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer callback.Close()
+
+	server, err := NewServer(ServerConfig{Port: 8080}, WithLogger(zap.NewNop()), WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.checkServiceAvailability(Service{Name: "orders", Hash: "abc", Callback: callback.URL})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	probe, check := spans[0], spans[1]
+	if check.Name != "goreg.health_check" || probe.Name != "HTTP GET" {
+		t.Fatalf("unexpected spans: %s, %s", check.Name, probe.Name)
+	}
+	if probe.Parent.SpanID() != check.SpanContext.SpanID() {
+		t.Errorf("probe span is not a child of the health check span")
+	}
+	var fullURL string
+	for _, attr := range probe.Attributes {
+		if attr.Key == "url.full" {
+			fullURL = attr.Value.AsString()
+		}
+	}
+	if fullURL != callback.URL {
+		t.Errorf("url.full = %s, want %s without the hash query", fullURL, callback.URL)
+	}
+	if traceparent == "" {
+		t.Fatal("expected traceparent header on the callback request")
+	}
+	if want := "00-" + probe.SpanContext.TraceID().String() + "-" + probe.SpanContext.SpanID().String() + "-01"; traceparent != want {
+		t.Errorf("traceparent = %s, want %s", traceparent, want)
+	}
+}
+
+func TestTracing_HandlerSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	server, err := NewServer(ServerConfig{Port: 8080}, WithLogger(zap.NewNop()), WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/get?name=missing", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "GET /get" {
+		t.Errorf("unexpected span name %q", spans[0].Name)
+	}
+}
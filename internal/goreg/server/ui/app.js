@@ -0,0 +1,209 @@
+"use strict";
+
+// The dashboard follows the registry with blocking queries on /v1/services:
+// each response carries X-Goreg-Index and the next request waits for the
+// index to move past it. Registry data is only ever set as textContent.
+(function () {
+  const tokenKey = "goreg.token";
+  const wait = "60s";
+  const retryDelay = 5000;
+  const maxEvents = 50;
+
+  const $ = (id) => document.getElementById(id);
+  const els = {
+    state: $("state"),
+    tokenForm: $("token-form"),
+    token: $("token"),
+    filter: $("status-filter"),
+    count: $("count"),
+    services: $("services"),
+    empty: $("services-empty"),
+    rowTemplate: $("service-row"),
+    events: $("events"),
+    eventsNote: $("events-note"),
+  };
+
+  let index = 0;
+  let services = [];
+  let flapping = new Set();
+  let inflight = null;
+
+  function token() {
+    return sessionStorage.getItem(tokenKey) || "";
+  }
+
+  async function api(method, path, signal) {
+    const headers = {};
+    if (token()) {
+      headers["X-Goreg-Token"] = token();
+    }
+
+    const res = await fetch(path, { method, headers, signal, cache: "no-store" });
+    if (!res.ok) {
+      let detail = res.statusText;
+      try {
+        detail = (await res.json()).detail || detail;
+      } catch (e) {
+        // not a problem document
+      }
+      const err = new Error(detail);
+      err.status = res.status;
+      throw err;
+    }
+    return res;
+  }
+
+  function sleep(ms) {
+    return new Promise((resolve) => setTimeout(resolve, ms));
+  }
+
+  function setState(text, kind) {
+    els.state.textContent = text;
+    els.state.className = "state " + (kind || "");
+  }
+
+  function chip(text) {
+    const span = document.createElement("span");
+    span.className = "chip";
+    span.textContent = text;
+    return span;
+  }
+
+  function target(service) {
+    try {
+      const callback = new URL(service.callback);
+      const host = service.address || callback.hostname;
+      const port = service.port || callback.port;
+      return port ? host + ":" + port : host;
+    } catch (e) {
+      return service.address || "";
+    }
+  }
+
+  function renderService(service) {
+    const row = els.rowTemplate.content.firstElementChild.cloneNode(true);
+    row.querySelector(".name").textContent = service.name;
+
+    const badge = row.querySelector(".badge");
+    badge.textContent = service.status;
+    badge.classList.add(service.status);
+    row.querySelector(".flapping").hidden = !flapping.has(service.hash);
+
+    const hash = row.querySelector(".hash");
+    hash.textContent = service.hash.slice(0, 8);
+    hash.title = service.hash;
+
+    row.querySelector(".target").textContent = target(service);
+    row.querySelector(".tags").append(...(service.tags || []).map(chip));
+    row.querySelector(".metadata").append(
+      ...Object.entries(service.metadata || {}).map(([key, value]) => chip(key + "=" + value)),
+    );
+    row.querySelector(".index").textContent = service.modify_index || "";
+    row.querySelector(".deregister").addEventListener("click", () => deregister(service.name));
+    return row;
+  }
+
+  function renderServices() {
+    const status = els.filter.value;
+    const shown = services.filter((service) => !status || service.status === status);
+
+    els.count.textContent = "(" + shown.length + ")";
+    els.empty.hidden = shown.length > 0;
+    els.services.replaceChildren(...shown.map(renderService));
+  }
+
+  function renderEvent(entry) {
+    const li = document.createElement("li");
+
+    const time = document.createElement("time");
+    time.dateTime = entry.time;
+    time.textContent = new Date(entry.time).toLocaleString();
+
+    let text = entry.action + " " + entry.service;
+    if (entry.action === "health" && entry.before && entry.after) {
+      text += ": " + entry.before.status + " → " + entry.after.status;
+    }
+    text += " by " + (entry.token_id || entry.actor);
+    if (entry.source_ip) {
+      text += " from " + entry.source_ip;
+    }
+
+    li.append(time, document.createTextNode(text));
+    return li;
+  }
+
+  async function refreshFlapping() {
+    try {
+      const res = await api("GET", "/v1/flapping");
+      flapping = new Set((await res.json()).map((instance) => instance.hash));
+    } catch (e) {
+      flapping = new Set();
+    }
+  }
+
+  async function refreshEvents() {
+    try {
+      const res = await api("GET", "/v1/audit");
+      const entries = await res.json();
+      els.eventsNote.hidden = true;
+      els.events.replaceChildren(...entries.slice(-maxEvents).reverse().map(renderEvent));
+    } catch (err) {
+      els.eventsNote.hidden = err.status !== 403;
+      els.events.replaceChildren();
+    }
+  }
+
+  async function deregister(name) {
+    if (!confirm("Deregister " + name + "?")) {
+      return;
+    }
+
+    try {
+      await api("DELETE", "/v1/services/" + encodeURIComponent(name));
+    } catch (err) {
+      alert("Deregister " + name + " failed: " + err.message);
+    }
+    // The blocking query wakes up on the change and redraws.
+  }
+
+  async function follow() {
+    for (;;) {
+      inflight = new AbortController();
+      try {
+        const res = await api("GET", "/v1/services?index=" + index + "&wait=" + wait, inflight.signal);
+        const next = Number(res.headers.get("X-Goreg-Index")) || 0;
+        services = await res.json();
+
+        await Promise.all([refreshFlapping(), refreshEvents()]);
+        renderServices();
+        setState("live", "live");
+
+        // An empty registry reports index 0, which never blocks.
+        if (next === 0) {
+          await sleep(retryDelay);
+        }
+        index = next;
+      } catch (err) {
+        if (err.name === "AbortError") {
+          continue;
+        }
+        setState(err.status === 403 ? "token lacks read access" : "disconnected: " + err.message, "error");
+        await sleep(retryDelay);
+      }
+    }
+  }
+
+  els.tokenForm.addEventListener("submit", (event) => {
+    event.preventDefault();
+    sessionStorage.setItem(tokenKey, els.token.value);
+    els.token.value = "";
+    index = 0;
+    if (inflight) {
+      inflight.abort();
+    }
+  });
+
+  els.filter.addEventListener("change", renderServices);
+
+  follow();
+})();
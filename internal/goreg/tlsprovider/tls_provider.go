@@ -0,0 +1,313 @@
+package tlsprovider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+const reloadInterval = 10 * time.Second
+
+// Config describes the certificate material of one side of a connection.
+// CertFile/KeyFile are served (or presented as a client certificate). CAFile
+// replaces the system roots verifying servers on outgoing requests;
+// ClientCAFile verifies client certificates and turns on mutual TLS on a
+// listener.
+type Config struct {
+	CertFile     string `yaml:"cert_file" json:"cert_file"`
+	KeyFile      string `yaml:"key_file" json:"key_file"`
+	CAFile       string `yaml:"ca_file" json:"ca_file"`
+	ClientCAFile string `yaml:"client_ca_file" json:"client_ca_file"`
+}
+
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.CAFile != "" || c.ClientCAFile != ""
+}
+
+func (c Config) HasCertificate() bool {
+	return c.CertFile != ""
+}
+
+func ValidateConfig(cfg Config) error {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return errors.New("tls cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// Reloader keeps the certificate and CA pools described by Config in memory
+// and re-reads them when the files change on disk, so rotated certificates are
+// picked up without a restart.
+type Reloader struct {
+	cfg      Config
+	interval time.Duration
+	now      func() time.Time
+
+	rwmu       *sync.RWMutex
+	cert       *tls.Certificate
+	pool       *x509.CertPool
+	clientPool *x509.CertPool
+	modTime    time.Time
+	checked    time.Time
+}
+
+func NewReloader(cfg Config) (*Reloader, error) {
+	if err := ValidateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		cfg:      cfg,
+		interval: reloadInterval,
+		now:      time.Now,
+		rwmu:     &sync.RWMutex{},
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Reloader) Reload() error {
+	var cert *tls.Certificate
+	if r.cfg.HasCertificate() {
+		pair, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			return errors.New("goreg->[tls]: load key pair: " + err.Error())
+		}
+		cert = &pair
+	}
+
+	pool, err := loadPool(r.cfg.CAFile)
+	if err != nil {
+		return err
+	}
+
+	clientPool, err := loadPool(r.cfg.ClientCAFile)
+	if err != nil {
+		return err
+	}
+
+	r.rwmu.Lock()
+	defer r.rwmu.Unlock()
+
+	r.cert = cert
+	r.pool = pool
+	r.clientPool = clientPool
+	r.modTime = r.latestModTime()
+	r.checked = r.now()
+
+	return nil
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.New("goreg->[tls]: read ca: " + err.Error())
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("goreg->[tls]: no certificates found in " + file)
+	}
+	return pool, nil
+}
+
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.maybeReload()
+
+	r.rwmu.RLock()
+	defer r.rwmu.RUnlock()
+	return r.cert
+}
+
+func (r *Reloader) CertPool() *x509.CertPool {
+	r.maybeReload()
+
+	r.rwmu.RLock()
+	defer r.rwmu.RUnlock()
+	return r.pool
+}
+
+func (r *Reloader) ClientCertPool() *x509.CertPool {
+	r.maybeReload()
+
+	r.rwmu.RLock()
+	defer r.rwmu.RUnlock()
+	return r.clientPool
+}
+
+// ServerTLSConfig builds a listener config. Every handshake gets the current
+// certificate and is verified against the current client CA pool; a
+// configured client CA makes client certificates mandatory.
+func (r *Reloader) ServerTLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if cert := r.Certificate(); cert != nil {
+				return cert, nil
+			}
+			return nil, errors.New("goreg->[tls]: no certificate configured")
+		},
+	}
+
+	if r.cfg.ClientCAFile != "" {
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyChain(cs.PeerCertificates, r.ClientCertPool(), x509.ExtKeyUsageClientAuth)
+		}
+	}
+
+	return cfg
+}
+
+func verifyChain(certs []*x509.Certificate, roots *x509.CertPool, usage x509.ExtKeyUsage) error {
+	if len(certs) == 0 {
+		return errors.New("goreg->[tls]: no peer certificate")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+// ClientTLSConfig builds the config of one outgoing connection to serverName
+// with the current root pool. It is meant to be built per connection, as
+// HTTPClient and TransportCredentials do, so a rotated CA applies to the next
+// handshake.
+func (r *Reloader) ClientTLSConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		RootCAs:    r.CertPool(),
+	}
+
+	if r.cfg.HasCertificate() {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := r.Certificate(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		}
+	}
+
+	return cfg
+}
+
+func (r *Reloader) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialTLSContext = r.dialTLS
+
+	return &http.Client{Transport: transport}
+}
+
+func (r *Reloader) dialTLS(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	raw, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := tls.Client(raw, r.ClientTLSConfig(host))
+	if err := conn.HandshakeContext(ctx); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// TransportCredentials are the gRPC counterpart of HTTPClient.
+func (r *Reloader) TransportCredentials() credentials.TransportCredentials {
+	return reloadingCredentials{
+		TransportCredentials: credentials.NewTLS(r.ClientTLSConfig("")),
+		reloader:             r,
+	}
+}
+
+type reloadingCredentials struct {
+	credentials.TransportCredentials
+	reloader *Reloader
+}
+
+func (c reloadingCredentials) ClientHandshake(ctx context.Context, authority string, raw net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.reloader.ClientTLSConfig("")).ClientHandshake(ctx, authority, raw)
+}
+
+func (c reloadingCredentials) Clone() credentials.TransportCredentials {
+	return reloadingCredentials{
+		TransportCredentials: c.TransportCredentials.Clone(),
+		reloader:             c.reloader,
+	}
+}
+
+func (r *Reloader) maybeReload() {
+	r.rwmu.RLock()
+	due := r.now().Sub(r.checked) >= r.interval
+	modTime := r.modTime
+	r.rwmu.RUnlock()
+
+	if !due {
+		return
+	}
+
+	if latest := r.latestModTime(); latest.Equal(modTime) {
+		r.rwmu.Lock()
+		r.checked = r.now()
+		r.rwmu.Unlock()
+		return
+	}
+
+	// A failed reload keeps serving the previous material; the next check
+	// retries once the files are consistent again.
+	if err := r.Reload(); err != nil {
+		r.rwmu.Lock()
+		r.checked = r.now()
+		r.rwmu.Unlock()
+	}
+}
+
+func (r *Reloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile, r.cfg.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
@@ -0,0 +1,369 @@
+package tlsprovider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "goreg test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64, name string) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writePair(t *testing.T, dir, name string, certPEM, keyPEM []byte) Config {
+	t.Helper()
+
+	cfg := Config{
+		CertFile: filepath.Join(dir, name+".crt"),
+		KeyFile:  filepath.Join(dir, name+".key"),
+	}
+	writeFile(t, cfg.CertFile, certPEM)
+	writeFile(t, cfg.KeyFile, keyPEM)
+	return cfg
+}
+
+func serve(t *testing.T, reloader *Reloader) string {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", reloader.ServerTLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	return "https://" + ln.Addr().String()
+}
+
+func TestValidateConfig(t *testing.T) {
+	if err := ValidateConfig(Config{CertFile: "cert.pem"}); err == nil {
+		t.Errorf("expected error for cert without key")
+	}
+
+	if err := ValidateConfig(Config{CAFile: "ca.pem"}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestNewReloader_MissingFiles(t *testing.T) {
+	if _, err := NewReloader(Config{CertFile: "missing.crt", KeyFile: "missing.key"}); err == nil {
+		t.Fatal("expected error for missing files, got nil")
+	}
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+
+	serverCert, serverKey := ca.issue(t, 2, "server")
+	serverCfg := writePair(t, dir, "server", serverCert, serverKey)
+	serverCfg.ClientCAFile = caFile
+
+	serverReloader, err := NewReloader(serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, serverReloader)
+
+	clientCert, clientKey := ca.issue(t, 3, "client")
+	clientCfg := writePair(t, dir, "client", clientCert, clientKey)
+	clientCfg.CAFile = caFile
+
+	clientReloader, err := NewReloader(clientCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := clientReloader.HTTPClient().Get(url)
+	if err != nil {
+		t.Fatalf("expected mutual TLS request to succeed, got %v", err)
+	}
+	res.Body.Close()
+
+	anonymous, err := NewReloader(Config{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res, err := anonymous.HTTPClient().Get(url); err == nil {
+		res.Body.Close()
+		t.Fatal("expected request without client certificate to fail")
+	}
+
+	otherCert, otherKey := newTestCA(t).issue(t, 4, "other")
+	otherCfg := writePair(t, dir, "other", otherCert, otherKey)
+	otherCfg.CAFile = caFile
+
+	other, err := NewReloader(otherCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res, err := other.HTTPClient().Get(url); err == nil {
+		res.Body.Close()
+		t.Fatal("expected client certificate of an unknown CA to be rejected")
+	}
+}
+
+func TestReloader_TransportCredentials(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+
+	serverCert, serverKey := ca.issue(t, 6, "server")
+	serverCfg := writePair(t, dir, "server", serverCert, serverKey)
+	serverCfg.ClientCAFile = caFile
+
+	serverReloader, err := NewReloader(serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverReloader.ServerTLSConfig())))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	clientCert, clientKey := ca.issue(t, 7, "client")
+	clientCfg := writePair(t, dir, "client", clientCert, clientKey)
+	clientCfg.CAFile = caFile
+
+	clientReloader, err := NewReloader(clientCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(clientReloader.TransportCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("expected mutual TLS call to succeed, got %v", err)
+	}
+}
+
+func TestReloader_CAFileDoesNotRequireClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+
+	serverCert, serverKey := ca.issue(t, 4, "server")
+	serverCfg := writePair(t, dir, "server", serverCert, serverKey)
+	serverCfg.CAFile = caFile
+
+	serverReloader, err := NewReloader(serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, serverReloader)
+
+	anonymous, err := NewReloader(Config{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := anonymous.HTTPClient().Get(url)
+	if err != nil {
+		t.Fatalf("expected request without client certificate to succeed, got %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestReloader_ReloadsRootCAs(t *testing.T) {
+	dir := t.TempDir()
+	oldCA, newCA := newTestCA(t), newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, oldCA.pem)
+
+	serverCert, serverKey := newCA.issue(t, 5, "server")
+	serverReloader, err := NewReloader(writePair(t, dir, "server", serverCert, serverKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, serverReloader)
+
+	reloader, err := NewReloader(Config{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloader.interval = 0
+	client := reloader.HTTPClient()
+
+	if res, err := client.Get(url); err == nil {
+		res.Body.Close()
+		t.Fatal("expected certificate of an unknown CA to be rejected")
+	}
+
+	writeFile(t, caFile, newCA.pem)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(caFile, later, later)
+
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("expected rotated CA to be used by the same client, got %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestReloader_HotReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+
+	certPEM, keyPEM := ca.issue(t, 10, "first")
+	cfg := writePair(t, dir, "server", certPEM, keyPEM)
+
+	reloader, err := NewReloader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloader.interval = 0
+
+	first := reloader.Certificate()
+
+	certPEM, keyPEM = ca.issue(t, 11, "second")
+	writePair(t, dir, "server", certPEM, keyPEM)
+
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(cfg.CertFile, later, later)
+	os.Chtimes(cfg.KeyFile, later, later)
+
+	second := reloader.Certificate()
+	if second == first {
+		t.Fatal("expected certificate to be reloaded")
+	}
+
+	leaf, err := x509.ParseCertificate(second.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if leaf.Subject.CommonName != "second" {
+		t.Errorf("expected reloaded certificate, got %s", leaf.Subject.CommonName)
+	}
+}
+
+func TestReloader_KeepsCertificateOnBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+
+	certPEM, keyPEM := ca.issue(t, 20, "server")
+	cfg := writePair(t, dir, "server", certPEM, keyPEM)
+
+	reloader, err := NewReloader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloader.interval = 0
+
+	writeFile(t, cfg.CertFile, []byte("garbage"))
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(cfg.CertFile, later, later)
+
+	if reloader.Certificate() == nil {
+		t.Fatal("expected previous certificate to be kept")
+	}
+}
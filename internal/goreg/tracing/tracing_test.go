@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func TestMiddleware_ContinuesRemoteTrace(t *testing.T) {
+	tp, exporter := newTestProvider()
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	var handlerSpan trace.SpanContext
+	handler := Middleware(Tracer(tp), "/get", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/get?name=orders", nil)
+	req.Header.Set("traceparent", traceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name != "GET /get" {
+		t.Errorf("unexpected span name %q", span.Name)
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("expected server span, got %v", span.SpanKind)
+	}
+	if got := span.Parent.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected remote trace id, got %s", got)
+	}
+	if span.SpanContext.SpanID() != handlerSpan.SpanID() {
+		t.Errorf("handler context does not carry the server span")
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("expected error status for 500 response, got %v", span.Status.Code)
+	}
+}
+
+func TestInject_NoopByDefault(t *testing.T) {
+	ctx, span := Tracer(nil).Start(context.Background(), "noop")
+	defer span.End()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	Inject(ctx, req)
+
+	if got := req.Header.Get("traceparent"); got != "" {
+		t.Errorf("expected no traceparent without a tracer provider, got %q", got)
+	}
+}
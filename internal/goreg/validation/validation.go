@@ -0,0 +1,172 @@
+package validation
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrRequired          = errors.New("is required")
+	ErrInvalidURL        = errors.New("is not a valid absolute url")
+	ErrUnsupportedScheme = errors.New("has unsupported scheme")
+	ErrOutOfRange        = errors.New("is out of range")
+	ErrInvalidName       = errors.New("is not a valid service name")
+	ErrInvalidCIDR       = errors.New("is not a valid CIDR")
+)
+
+const (
+	MinPort = 1
+	MaxPort = 65535
+
+	maxNameLength = 63
+)
+
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?$`)
+
+// FieldError describes one invalid config field. Err is one of the Err*
+// sentinels, so callers can both errors.As the field and errors.Is the reason.
+type FieldError struct {
+	Field  string
+	Value  any
+	Err    error
+	Detail string
+}
+
+func (e *FieldError) Error() string {
+	msg := e.Field + " invalid: " + e.Err.Error()
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// URL checks that raw is an absolute url with a host and one of schemes
+// (http and https when none are given).
+func URL(field, raw string, schemes ...string) error {
+	if raw == "" {
+		return &FieldError{Field: field, Value: raw, Err: ErrRequired}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return &FieldError{Field: field, Value: raw, Err: ErrInvalidURL}
+	}
+
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			if port := u.Port(); port != "" {
+				if n, err := strconv.Atoi(port); err != nil || n < MinPort || n > MaxPort {
+					return &FieldError{Field: field, Value: raw, Err: ErrOutOfRange, Detail: "port " + port}
+				}
+			}
+			return nil
+		}
+	}
+
+	return &FieldError{Field: field, Value: raw, Err: ErrUnsupportedScheme, Detail: u.Scheme}
+}
+
+func Port(field string, port int) error {
+	if port < MinPort || port > MaxPort {
+		return &FieldError{
+			Field:  field,
+			Value:  port,
+			Err:    ErrOutOfRange,
+			Detail: "must be between " + strconv.Itoa(MinPort) + " and " + strconv.Itoa(MaxPort),
+		}
+	}
+	return nil
+}
+
+// ServiceName checks that name can be used as a single DNS label and as a url
+// path segment.
+func ServiceName(field, name string) error {
+	if name == "" {
+		return &FieldError{Field: field, Value: name, Err: ErrRequired}
+	}
+
+	if len(name) > maxNameLength || !nameRe.MatchString(name) {
+		return &FieldError{
+			Field:  field,
+			Value:  name,
+			Err:    ErrInvalidName,
+			Detail: "letters, digits and inner hyphens, up to " + strconv.Itoa(maxNameLength) + " characters",
+		}
+	}
+	return nil
+}
+
+// CIDR checks that raw is an IPv4 or IPv6 prefix such as 10.0.0.0/8.
+func CIDR(field, raw string) error {
+	if _, _, err := net.ParseCIDR(raw); err != nil {
+		return &FieldError{Field: field, Value: raw, Err: ErrInvalidCIDR}
+	}
+	return nil
+}
+
+// Duration checks that d lies within [min, max]. A zero duration is accepted
+// and means "use the default".
+func Duration(field string, d, min, max time.Duration) error {
+	if d == 0 {
+		return nil
+	}
+
+	if d < min || d > max {
+		return &FieldError{
+			Field:  field,
+			Value:  d,
+			Err:    ErrOutOfRange,
+			Detail: "must be between " + min.String() + " and " + max.String(),
+		}
+	}
+	return nil
+}
+
+// Code returns a machine readable name of the sentinel err wraps, or
+// "invalid" for any other error.
+func Code(err error) string {
+	switch {
+	case errors.Is(err, ErrRequired):
+		return "required"
+	case errors.Is(err, ErrInvalidURL):
+		return "invalid_url"
+	case errors.Is(err, ErrUnsupportedScheme):
+		return "unsupported_scheme"
+	case errors.Is(err, ErrOutOfRange):
+		return "out_of_range"
+	case errors.Is(err, ErrInvalidName):
+		return "invalid_name"
+	case errors.Is(err, ErrInvalidCIDR):
+		return "invalid_cidr"
+	}
+	return "invalid"
+}
+
+// FieldErrors flattens err, including errors.Join trees, into its field
+// errors.
+func FieldErrors(err error) []*FieldError {
+	var out []*FieldError
+	switch e := err.(type) {
+	case *FieldError:
+		out = append(out, e)
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			out = append(out, FieldErrors(inner)...)
+		}
+	case interface{ Unwrap() error }:
+		out = FieldErrors(e.Unwrap())
+	}
+	return out
+}
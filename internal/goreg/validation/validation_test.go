@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr error
+	}{
+		{"Valid http", "http://registry:8079/set", nil},
+		{"Valid https", "https://some-api.com", nil},
+		{"Empty", "", ErrRequired},
+		{"Not absolute", "abc", ErrInvalidURL},
+		{"Missing host", "http://", ErrInvalidURL},
+		{"Unsupported scheme", "ftp://registry", ErrUnsupportedScheme},
+		{"Port out of range", "http://registry:99999", ErrOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := URL("address", tt.raw)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Errorf("URL() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPort(t *testing.T) {
+	tests := []struct {
+		port      int
+		wantError bool
+	}{
+		{1, false},
+		{8080, false},
+		{65535, false},
+		{0, true},
+		{-1, true},
+		{99999, true},
+	}
+
+	for _, tt := range tests {
+		err := Port("port", tt.port)
+		if (err != nil) != tt.wantError {
+			t.Errorf("Port(%d) error = %v, wantError %v", tt.port, err, tt.wantError)
+		}
+	}
+}
+
+func TestCIDR(t *testing.T) {
+	tests := []struct {
+		cidr      string
+		wantError bool
+	}{
+		{"10.0.0.0/8", false},
+		{"fd00::/8", false},
+		{"10.0.0.1", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		err := CIDR("cidr", tt.cidr)
+		if (err != nil) != tt.wantError {
+			t.Errorf("CIDR(%q) error = %v, wantError %v", tt.cidr, err, tt.wantError)
+		}
+	}
+}
+
+func TestServiceName(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantError bool
+	}{
+		{"orders", false},
+		{"payments-api", false},
+		{"testService", false},
+		{"0f8fad5b-d9cb-469f-a165-70867728950e", false},
+		{"", true},
+		{"with space", true},
+		{"with/slash", true},
+		{"-leading", true},
+		{"trailing-", true},
+		{"under_score", true},
+		{"a123456789012345678901234567890123456789012345678901234567890123", true},
+	}
+
+	for _, tt := range tests {
+		err := ServiceName("name", tt.name)
+		if (err != nil) != tt.wantError {
+			t.Errorf("ServiceName(%q) error = %v, wantError %v", tt.name, err, tt.wantError)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	if err := Duration("interval", 0, time.Second, time.Minute); err != nil {
+		t.Errorf("expected zero duration to be accepted, got %v", err)
+	}
+
+	if err := Duration("interval", 30*time.Second, time.Second, time.Minute); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if err := Duration("interval", time.Hour, time.Second, time.Minute); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("expected ErrOutOfRange, got %v", err)
+	}
+}
+
+func TestFieldError_As(t *testing.T) {
+	err := errors.Join(
+		Port("port", 99999),
+		ServiceName("name", "bad name"),
+	)
+
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected FieldError, got %v", err)
+	}
+
+	if fieldErr.Field != "port" || fieldErr.Value != 99999 {
+		t.Errorf("unexpected field error: %+v", fieldErr)
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	err := errors.Join(
+		Port("port", 99999),
+		errors.Join(ServiceName("name", ""), URL("callback", "ftp://x")),
+		errors.New("unrelated"),
+	)
+
+	fields := FieldErrors(err)
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 field errors, got %v", fields)
+	}
+
+	want := []string{"out_of_range", "required", "unsupported_scheme"}
+	for i, fieldErr := range fields {
+		if code := Code(fieldErr); code != want[i] {
+			t.Errorf("field %s: expected code %s, got %s", fieldErr.Field, want[i], code)
+		}
+	}
+}